@@ -0,0 +1,123 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// CopyAcross copies the src file of srcFs to the dst file, such as between buckets or regions.
+// A server-side copy is attempted first. When it is refused, such as with CrossLocationLoggingProhibited,
+// NotImplemented from S3 compatible stores or AccessDenied with srcFs using another client,
+// the content is streamed from srcFs instead,
+// keeping the content type, encoding, language and user metadata of the source.
+// Any other error of the server-side copy is returned.
+func (f *Fs) CopyAcross(ctx context.Context, srcFs *Fs, src, dst string) error {
+	if err := f.checkKey("copy", dst); err != nil {
+		return err
+	}
+
+	defer f.invalidate(f.cleanPath(dst))
+
+	err := f.copyAcrossServerSide(ctx, srcFs, src, dst)
+	if err == nil {
+		return nil
+	}
+
+	if isNotFound(err) {
+		return &fs.PathError{Op: "copy", Path: src, Err: fs.ErrNotExist}
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return errors.Join(ctxErr, mapError(err))
+	}
+
+	if !f.copyRefused(srcFs, err) {
+		return &fs.PathError{Op: "copy", Path: src, Err: mapError(err)}
+	}
+
+	f.notice("copy", "server-side copy refused, streamed: "+err.Error())
+
+	if err := f.copyAcrossStreaming(ctx, srcFs, src, dst); err != nil {
+		return &fs.PathError{Op: "copy", Path: src, Err: err}
+	}
+
+	return nil
+}
+
+// copyRefusedCodes are the error codes of a server-side copy the store does not allow.
+var copyRefusedCodes = map[string]struct{}{
+	"CrossLocationLoggingProhibited": {},
+	"NotImplemented":                 {},
+}
+
+// copyRefused reports whether err refuses the server-side copy from srcFs, rather than failing it.
+// AccessDenied is a refusal only when srcFs uses another client, such as of another account or region,
+// as the credentials of f may then not read the source.
+func (f *Fs) copyRefused(srcFs *Fs, err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	if _, found := copyRefusedCodes[apiErr.ErrorCode()]; found {
+		return true
+	}
+
+	return apiErr.ErrorCode() == "AccessDenied" && baseClient(f.client) != baseClient(srcFs.client)
+}
+
+func (f *Fs) copyAcrossServerSide(ctx context.Context, srcFs *Fs, src, dst string) error {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	_, err := f.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(f.bucket),
+		Key:        aws.String(f.withPrefix(dst)),
+		CopySource: aws.String(srcFs.copySource(src)),
+		ACL:        f.acl,
+	})
+
+	return err
+}
+
+// copyAcrossStreaming downloads the src file of srcFs while uploading it to the dst file.
+func (f *Fs) copyAcrossStreaming(ctx context.Context, srcFs *Fs, src, dst string) error {
+	meta, err := srcFs.HeadersWithContext(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	r, err := srcFs.OpenReaderWithContext(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	uploader := manager.NewUploader(f.client, func(u *manager.Uploader) {
+		u.Concurrency = 1
+		u.PartSize = f.uploadPartSize
+	})
+
+	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:            aws.String(f.bucket),
+		Key:               aws.String(f.withPrefix(dst)),
+		Body:              f.limitReader(ctx, r),
+		ACL:               f.acl,
+		ContentType:       nonEmpty(meta.ContentType),
+		ContentEncoding:   nonEmpty(meta.ContentEncoding),
+		ContentLanguage:   nonEmpty(meta.ContentLanguage),
+		Metadata:          meta.Metadata,
+		ChecksumAlgorithm: f.uploadChecksum,
+	})
+
+	return mapError(err)
+}
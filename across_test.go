@@ -0,0 +1,155 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+// crossLocationClient refuses every server-side copy.
+type crossLocationClient struct {
+	*s3fstest.Client
+}
+
+func (c crossLocationClient) CopyObject(context.Context, *s3.CopyObjectInput, ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return nil, s3fstest.Error("CopyObject", http.StatusForbidden, &smithy.GenericAPIError{Code: "CrossLocationLoggingProhibited", Message: "Cross S3 location logging not allowed."})
+}
+
+func TestCopyAcross(t *testing.T) {
+	tests := []struct {
+		client    func(*s3fstest.Client) s3ApiClient
+		name      string
+		wantPuts  int
+		streaming bool
+	}{
+		{name: "server-side", client: func(c *s3fstest.Client) s3ApiClient { return c }},
+		{name: "streaming", client: func(c *s3fstest.Client) s3ApiClient { return crossLocationClient{c} }, wantPuts: 1, streaming: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := s3fstest.New()
+			client.CreateBucket("src")
+			client.CreateBucket("dst")
+
+			data := bytes.Repeat([]byte("a"), 1024)
+			_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+				Bucket:      aws.String("src"),
+				Key:         aws.String("in/file.json"),
+				Body:        bytes.NewReader(data),
+				ContentType: aws.String("application/json"),
+				Metadata:    map[string]string{"owner": "team"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.Reset()
+
+			var notices []string
+			srcFs := New(client, "src", WithPrefix("in"))
+			dstFs := New(tt.client(client), "dst", WithPrefix("out"), WithFallbackNotice(func(op, reason string) {
+				notices = append(notices, op)
+			}))
+
+			if err := dstFs.CopyAcross(context.Background(), srcFs, "file.json", "copy.json"); err != nil {
+				t.Fatal(err)
+			}
+
+			obj, found := client.Object("dst", "out/copy.json")
+			if !found {
+				t.Fatal("out/copy.json not found")
+			}
+
+			if !bytes.Equal(obj.Data, data) || aws.ToString(obj.ContentType) != "application/json" || obj.Metadata["owner"] != "team" {
+				t.Errorf("object = (%d bytes, %q, %v), want (%d bytes, application/json, owner=team)", len(obj.Data), aws.ToString(obj.ContentType), obj.Metadata, len(data))
+			}
+
+			if got := client.Count("PutObject"); got != tt.wantPuts {
+				t.Errorf("PutObject calls = %d, want %d", got, tt.wantPuts)
+			}
+
+			if got := len(notices) > 0; got != tt.streaming {
+				t.Errorf("notices = %v, want streaming %v", notices, tt.streaming)
+			}
+		})
+	}
+}
+
+func TestCopyAcrossNotExist(t *testing.T) {
+	client := s3fstest.New()
+	client.CreateBucket("src")
+	client.CreateBucket("dst")
+
+	for _, c := range []s3ApiClient{client, crossLocationClient{client}} {
+		err := New(c, "dst").CopyAcross(context.Background(), New(client, "src"), "missing", "copy")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%T: CopyAcross() error = %v, want %v", c, err, fs.ErrNotExist)
+		}
+	}
+
+	if keys := client.Keys("dst"); len(keys) != 0 {
+		t.Errorf("keys = %v, want none", keys)
+	}
+}
+
+// copyErrorClient fails every server-side copy with err.
+type copyErrorClient struct {
+	*s3fstest.Client
+	err error
+}
+
+func (c copyErrorClient) CopyObject(context.Context, *s3.CopyObjectInput, ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return nil, c.err
+}
+
+func TestCopyAcrossErrors(t *testing.T) {
+	accessDenied := s3fstest.Error("CopyObject", http.StatusForbidden, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"})
+
+	tests := []struct {
+		err        error
+		name       string
+		sameClient bool
+		streaming  bool
+	}{
+		{name: "not implemented", err: s3fstest.Error("CopyObject", http.StatusNotImplemented, &smithy.GenericAPIError{Code: "NotImplemented"}), sameClient: true, streaming: true},
+		{name: "access denied across clients", err: accessDenied, streaming: true},
+		{name: "access denied", err: accessDenied, sameClient: true},
+		{name: "throttled", err: s3fstest.Error("CopyObject", http.StatusServiceUnavailable, &smithy.GenericAPIError{Code: "SlowDown"}), sameClient: true},
+		{name: "internal error", err: s3fstest.Error("CopyObject", http.StatusInternalServerError, &smithy.GenericAPIError{Code: "InternalError"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := s3fstest.New()
+			client.CreateBucket("src")
+			client.CreateBucket("dst")
+			client.Put("src", "file", []byte("data"))
+
+			dstClient := copyErrorClient{client, tt.err}
+			var srcClient s3ApiClient = client
+			if tt.sameClient {
+				srcClient = dstClient
+			}
+
+			err := New(dstClient, "dst").CopyAcross(context.Background(), New(srcClient, "src"), "file", "copy")
+
+			_, found := client.Object("dst", "copy")
+			if found != tt.streaming {
+				t.Errorf("copy found = %v, want %v", found, tt.streaming)
+			}
+
+			if gotErr := err != nil; gotErr == tt.streaming {
+				t.Errorf("CopyAcross() error = %v, want error %v", err, !tt.streaming)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package s3fs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// WithChecksumValidation sets the ChecksumMode of downloads.
+// With types.ChecksumModeEnabled, a file read from the start is compared with the
+// SHA256, CRC32C or CRC32 checksum stored with the object, and the last Read returns
+// ErrChecksumMismatch if they differ.
+// Objects without a checksum, or with a checksum of the parts (from multipart uploads), are not verified.
+func WithChecksumValidation(mode types.ChecksumMode) Option {
+	return func(f *Fs) {
+		f.checksumMode = mode
+	}
+}
+
+// WithUploadChecksum sets the algorithm of the checksum computed on upload,
+// which S3 verifies and stores with the object for later validation.
+func WithUploadChecksum(alg types.ChecksumAlgorithm) Option {
+	return func(f *Fs) {
+		f.uploadChecksum = alg
+	}
+}
+
+// Checksum returns the checksum of the named file computed by S3 with the algorithm, without downloading it.
+// Checksums of multipart uploads are checksums of the parts checksums.
+// It returns ErrNoChecksum if the object has no checksum of the algorithm.
+func (f *Fs) Checksum(name string, algo types.ChecksumAlgorithm) ([]byte, error) {
+	return f.ChecksumWithContext(context.Background(), name, algo)
+}
+
+// ChecksumWithContext returns the checksum of the named file computed by S3 with the algorithm, without downloading it.
+// Checksums of multipart uploads are checksums of the parts checksums.
+// It returns ErrNoChecksum if the object has no checksum of the algorithm.
+func (f *Fs) ChecksumWithContext(ctx context.Context, name string, algo types.ChecksumAlgorithm) ([]byte, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(f.bucket),
+		Key:          aws.String(f.withPrefix(name)),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, &fs.PathError{Op: "checksum", Path: name, Err: fs.ErrNotExist}
+		}
+		return nil, mapError(err)
+	}
+
+	var encoded string
+	switch algo {
+	case types.ChecksumAlgorithmCrc32:
+		encoded = aws.ToString(res.ChecksumCRC32)
+	case types.ChecksumAlgorithmCrc32c:
+		encoded = aws.ToString(res.ChecksumCRC32C)
+	case types.ChecksumAlgorithmSha1:
+		encoded = aws.ToString(res.ChecksumSHA1)
+	case types.ChecksumAlgorithmSha256:
+		encoded = aws.ToString(res.ChecksumSHA256)
+	}
+
+	// checksums of multipart uploads are suffixed with the number of parts
+	encoded, _, _ = strings.Cut(encoded, "-")
+	if encoded == "" {
+		return nil, &fs.PathError{Op: "checksum", Path: name, Err: fmt.Errorf("%w: %s", ErrNoChecksum, algo)}
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, &fs.PathError{Op: "checksum", Path: name, Err: err}
+	}
+
+	return sum, nil
+}
+
+// checksumWriterAt hashes the bytes written in order,
+// as the downloader does with a single part at a time.
+type checksumWriterAt struct {
+	io.WriterAt
+	hash     hash.Hash
+	expected string
+	offset   int64
+}
+
+// checksumWriter returns a writer hashing the content of the named file written to w,
+// or nil if the object has no checksum to compare with.
+func (f *Fs) checksumWriter(ctx context.Context, name string, w io.WriterAt) (*checksumWriterAt, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(f.bucket),
+		Key:          aws.String(f.withPrefix(name)),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		h        hash.Hash
+		expected string
+	)
+
+	switch {
+	case aws.ToString(res.ChecksumSHA256) != "":
+		h, expected = sha256.New(), aws.ToString(res.ChecksumSHA256)
+	case aws.ToString(res.ChecksumCRC32C) != "":
+		h, expected = crc32.New(crc32.MakeTable(crc32.Castagnoli)), aws.ToString(res.ChecksumCRC32C)
+	case aws.ToString(res.ChecksumCRC32) != "":
+		h, expected = crc32.NewIEEE(), aws.ToString(res.ChecksumCRC32)
+	default:
+		return nil, nil
+	}
+
+	// checksums of multipart uploads are suffixed with the number of parts
+	if strings.Contains(expected, "-") {
+		return nil, nil
+	}
+
+	return &checksumWriterAt{WriterAt: w, hash: h, expected: expected}, nil
+}
+
+func (w *checksumWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.WriterAt.WriteAt(p, off)
+	if off == w.offset {
+		w.hash.Write(p[:n])
+		w.offset += int64(n)
+	} else {
+		// out of order writes can not be verified
+		w.offset = -1
+	}
+
+	return n, err
+}
+
+// verify compares the checksum of the bytes written with the one of the object.
+func (w *checksumWriterAt) verify(name string) error {
+	if w.offset < 0 {
+		return nil
+	}
+
+	if got := base64.StdEncoding.EncodeToString(w.hash.Sum(nil)); got != w.expected {
+		return &fs.PathError{Op: "read", Path: name, Err: fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, got, w.expected)}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+// checksumClient advertises a SHA256 checksum on HeadObject, regardless of the content.
+type checksumClient struct {
+	*s3fstest.Client
+	checksum string
+}
+
+func (c checksumClient) HeadObject(ctx context.Context, in *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	out, err := c.Client.HeadObject(ctx, in, optFns...)
+	if err == nil && in.ChecksumMode == types.ChecksumModeEnabled {
+		out.ChecksumSHA256 = aws.String(c.checksum)
+	}
+	return out, err
+}
+
+func sha256Checksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestChecksumValidation(t *testing.T) {
+	tests := []struct {
+		wantErr  error
+		name     string
+		checksum string
+		mode     types.ChecksumMode
+	}{
+		{
+			name:     "match",
+			checksum: sha256Checksum("content"),
+			mode:     types.ChecksumModeEnabled,
+		},
+		{
+			name:     "mismatch",
+			checksum: sha256Checksum("corrupted"),
+			mode:     types.ChecksumModeEnabled,
+			wantErr:  ErrChecksumMismatch,
+		},
+		{
+			name:     "multipart checksum",
+			checksum: sha256Checksum("corrupted") + "-2",
+			mode:     types.ChecksumModeEnabled,
+		},
+		{
+			name:     "disabled",
+			checksum: sha256Checksum("corrupted"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := s3fstest.New()
+			client.Put("test", "file", []byte("content"))
+			fsys := New(checksumClient{Client: client, checksum: tt.checksum}, "test", WithChecksumValidation(tt.mode))
+
+			f, err := fsys.Open("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = f.Close() }()
+
+			got, err := io.ReadAll(f)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if string(got) != "content" {
+				t.Errorf("ReadAll() = %q, want %q", got, "content")
+			}
+		})
+	}
+}
+
+func TestChecksumValidationAfterSeek(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "file", []byte("content"))
+	fsys := New(checksumClient{Client: client, checksum: sha256Checksum("corrupted")}, "test", WithChecksumValidation(types.ChecksumModeEnabled))
+
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// a partial read can not be verified
+	if _, err := f.(*File).Seek(3, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "tent" {
+		t.Errorf("ReadAll() = %q, want %q", got, "tent")
+	}
+}
+
+func TestUploadChecksum(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		size int
+	}{
+		{
+			name: "single part",
+			size: 1024,
+			want: "OrlqYg==",
+		},
+		{
+			name: "multipart",
+			size: 6 * 1024 * 1024,
+			want: "-2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t, WithUploadChecksum(types.ChecksumAlgorithmCrc32c), WithChecksumValidation(types.ChecksumModeEnabled))
+			data := bytes.Repeat([]byte("a"), tt.size)
+
+			f, err := fsys.Create("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Write(data); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := client.GetObjectAttributes(context.Background(), &s3.GetObjectAttributesInput{
+				Bucket:           aws.String("test"),
+				Key:              aws.String("file"),
+				ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesChecksum},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := aws.ToString(res.Checksum.ChecksumCRC32C); !strings.HasSuffix(got, tt.want) {
+				t.Errorf("ChecksumCRC32C = %q, want %q", got, tt.want)
+			}
+
+			r, err := fsys.Open("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = r.Close() }()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, data) {
+				t.Errorf("ReadAll() = %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	want := sha256.Sum256([]byte("content"))
+
+	client := s3fstest.New()
+	client.Put("test", "file", []byte("content"))
+	fsys := New(checksumClient{Client: client, checksum: sha256Checksum("content")}, "test")
+
+	got, err := fsys.Checksum("file", types.ChecksumAlgorithmSha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want[:]) {
+		t.Errorf("Checksum() = %x, want %x", got, want)
+	}
+
+	if calls := client.Calls(); len(calls) != 1 || calls[0].Op != "HeadObject" {
+		t.Errorf("calls = %v, want a single HeadObject", calls)
+	}
+
+	if _, err := fsys.Checksum("file", types.ChecksumAlgorithmCrc32c); !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("Checksum(CRC32C) error = %v, want %v", err, ErrNoChecksum)
+	}
+
+	if _, err := fsys.Checksum("missing", types.ChecksumAlgorithmSha256); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Checksum(missing) error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
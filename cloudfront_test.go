@@ -0,0 +1,129 @@
+package s3fs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(rawURL string, expires time.Time) (string, error) {
+	return rawURL + "?Expires=" + expires.UTC().Format(time.RFC3339) + "&Signature=signed", nil
+}
+
+func TestCloudFrontOpenReader(t *testing.T) {
+	var requests []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.EscapedPath())
+
+		switch {
+		case r.URL.Query().Get("Signature") != "signed":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/prefix/dir/a file":
+			_, _ = io.WriteString(w, "content")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, client := newTestFs(t, WithPrefix("prefix"), WithCloudFront(u.Host, fakeSigner{}), WithHTTPClient(srv.Client()))
+
+	r, err := fsys.OpenReader("dir/a file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "content" {
+		t.Errorf("content = %q, want %q", got, "content")
+	}
+
+	if _, err := fsys.OpenReader("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenReader(missing) error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	want := []string{"/prefix/dir/a%20file", "/prefix/missing"}
+	if len(requests) != len(want) || requests[0] != want[0] || requests[1] != want[1] {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+
+	if got := len(client.Calls()); got != 0 {
+		t.Errorf("S3 calls = %d, want 0", got)
+	}
+}
+
+func TestCloudFrontAutoDecompress(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := io.WriteString(gz, "content"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// served as stored in S3, whatever the Accept-Encoding
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []byte
+	}{
+		{name: "decompressed", opts: []Option{WithAutoDecompress()}, want: []byte("content")},
+		{name: "as stored", want: compressed.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithCloudFront(u.Host, fakeSigner{}), WithHTTPClient(srv.Client())}, tt.opts...)
+			fsys, _ := newTestFs(t, opts...)
+
+			r, err := fsys.OpenReader("file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CopyTreeConcurrent copies every file under the src directory to the dst directory,
+// with up to concurrency server-side copies running at once,
+// files larger than the CopyObject limit of 5 GiB are copied in parts.
+// Files already in dst with the same size and ETag are skipped,
+// so running it again resumes an interrupted copy.
+// The ETag is the MD5 of the content only for objects uploaded in a single part,
+// a multipart object and its copy have different ETags, so such files are always copied again.
+// A failed copy does not stop the others, the errors are joined.
+func (f *Fs) CopyTreeConcurrent(ctx context.Context, src, dst string, concurrency int) error {
+	srcKeys, err := f.listKeys(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	if len(srcKeys) == 0 {
+		return &fs.PathError{Op: "copy", Path: src, Err: fs.ErrNotExist}
+	}
+
+	dstKeys, err := f.listKeys(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]walkKey, len(dstKeys))
+	for _, key := range dstKeys {
+		existing[key.name] = key
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	keys := make(chan walkKey)
+
+	for i := 0; i < max(concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for key := range keys {
+				name := key.name
+				err := f.checkKey("copy", f.join(dst, name))
+				if err == nil {
+					err = f.copySized(ctx, f.withPrefix(f.join(src, name)), f.join(dst, name), key.size)
+				}
+				f.invalidate(f.join(dst, name))
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, &fs.PathError{Op: "copy", Path: f.join(src, name), Err: err})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	// once ctx is done, the remaining keys are not handed to the workers
+dispatch:
+	for _, key := range srcKeys {
+		if d, found := existing[key.name]; found && d.size == key.size && d.etag == key.etag {
+			continue
+		}
+
+		select {
+		case keys <- key:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	close(keys)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// CopyDirEntry copies the file of a DirEntry listed by the Fs, such as by ReadDir, to dst.
+// The size of the entry chooses between a single server-side copy and a multipart copy,
+// without a Stat of the source.
+// ErrInvalid is returned for entries not listed by the Fs.
+func (f *Fs) CopyDirEntry(e fs.DirEntry, dst string) error {
+	return f.CopyDirEntryWithContext(context.Background(), e, dst)
+}
+
+// CopyDirEntryWithContext copies the file of a DirEntry listed by the Fs, such as by ReadDir, to dst.
+// The size of the entry chooses between a single server-side copy and a multipart copy,
+// without a Stat of the source.
+// ErrInvalid is returned for entries not listed by the Fs.
+func (f *Fs) CopyDirEntryWithContext(ctx context.Context, e fs.DirEntry, dst string) error {
+	if e.IsDir() {
+		return &fs.PathError{Op: "copy", Path: e.Name(), Err: ErrIsDirectory}
+	}
+
+	info, err := e.Info()
+	if err != nil {
+		return err
+	}
+
+	// the entry name is relative to its directory, the listed object has the full key
+	obj, ok := info.Sys().(types.Object)
+	if !ok || obj.Key == nil {
+		return &fs.PathError{Op: "copy", Path: e.Name(), Err: fs.ErrInvalid}
+	}
+
+	if err := f.checkKey("copy", dst); err != nil {
+		return err
+	}
+
+	defer f.invalidate(f.cleanPath(dst))
+
+	if err := f.copySized(ctx, aws.ToString(obj.Key), dst, info.Size()); err != nil {
+		return &fs.PathError{Op: "copy", Path: e.Name(), Err: err}
+	}
+
+	return nil
+}
+
+// copySized copies the object with the src key and size to the dst file,
+// with a multipart copy when it is larger than the CopyObject limit.
+func (f *Fs) copySized(ctx context.Context, src, dst string, size int64) error {
+	if size <= MaxPartSize {
+		return f.copyKey(ctx, src, dst)
+	}
+
+	f.notice("copy", "larger than the CopyObject limit, copied in parts")
+
+	return f.copyParts(ctx, src, dst, size, max(f.uploadPartSize, (size+MaxParts-1)/MaxParts))
+}
+
+// copyKey copies the object with the src key to the dst file with a server-side copy.
+func (f *Fs) copyKey(ctx context.Context, src, dst string) error {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	_, err := f.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(f.bucket),
+		Key:        aws.String(f.withPrefix(dst)),
+		CopySource: aws.String(copySource(f.bucket, src)),
+		ACL:        f.acl,
+	})
+
+	return mapError(err)
+}
+
+// copyParts copies the object with the src key and size to the dst file
+// with a multipart server-side copy of partSize parts, for objects too large for CopyObject.
+// The headers and metadata of the source are kept, as CopyObject does.
+func (f *Fs) copyParts(ctx context.Context, src, dst string, size, partSize int64) error {
+	head, err := f.headKey(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	createCtx, cancelFn := ctx, context.CancelFunc(func() {})
+	if f.timeout > 0 {
+		createCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+	}
+
+	upload, err := f.client.CreateMultipartUpload(createCtx, &s3.CreateMultipartUploadInput{
+		Bucket:             aws.String(f.bucket),
+		Key:                aws.String(f.withPrefix(dst)),
+		ACL:                f.acl,
+		CacheControl:       head.CacheControl,
+		ContentDisposition: head.ContentDisposition,
+		ContentEncoding:    head.ContentEncoding,
+		ContentLanguage:    head.ContentLanguage,
+		ContentType:        head.ContentType,
+		Metadata:           head.Metadata,
+	})
+	cancelFn()
+	if err != nil {
+		return mapError(err)
+	}
+
+	// the parts copied so far are discarded on failure, even if ctx is done
+	abort := func() {
+		abortCtx, cancelFn := context.WithoutCancel(ctx), context.CancelFunc(func() {})
+		if f.timeout > 0 {
+			abortCtx, cancelFn = context.WithTimeout(abortCtx, f.timeout)
+		}
+		defer cancelFn()
+
+		_, _ = f.client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(f.bucket),
+			Key:      aws.String(f.withPrefix(dst)),
+			UploadId: upload.UploadId,
+		})
+	}
+
+	var parts []types.CompletedPart
+
+	for offset, n := int64(0), int32(1); offset < size; offset, n = offset+partSize, n+1 {
+		part, err := f.copyPart(ctx, upload.UploadId, src, dst, n, offset, min(offset+partSize, size)-1)
+		if err != nil {
+			abort()
+			return err
+		}
+		parts = append(parts, part)
+	}
+
+	completeCtx, cancelFn := ctx, context.CancelFunc(func() {})
+	if f.timeout > 0 {
+		completeCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+	}
+	defer cancelFn()
+
+	_, err = f.client.CompleteMultipartUpload(completeCtx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(f.bucket),
+		Key:             aws.String(f.withPrefix(dst)),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		abort()
+		return mapError(err)
+	}
+
+	return nil
+}
+
+// headKey returns the HeadObject of the object with the key.
+func (f *Fs) headKey(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrNotExist
+		}
+		return nil, mapError(err)
+	}
+
+	return res, nil
+}
+
+// copyPart copies the bytes first to last of the object with the src key as the part n of an upload.
+func (f *Fs) copyPart(ctx context.Context, uploadID *string, src, dst string, n int32, first, last int64) (types.CompletedPart, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+		Bucket:          aws.String(f.bucket),
+		Key:             aws.String(f.withPrefix(dst)),
+		UploadId:        uploadID,
+		PartNumber:      aws.Int32(n),
+		CopySource:      aws.String(copySource(f.bucket, src)),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", first, last)),
+	})
+	if err != nil {
+		return types.CompletedPart{}, mapError(err)
+	}
+
+	// the ETag of the part is required to complete the upload
+	if res.CopyPartResult == nil {
+		return types.CompletedPart{}, fmt.Errorf("upload part copy %d: no copy result", n)
+	}
+
+	return types.CompletedPart{ETag: res.CopyPartResult.ETag, PartNumber: aws.Int32(n)}, nil
+}
@@ -0,0 +1,344 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func putTree(client *s3fstest.Client, dir string, n int) {
+	for i := 0; i < n; i++ {
+		client.Put("test", fmt.Sprintf("%s/d%d/file%04d", dir, i%10, i), []byte(fmt.Sprint(i)))
+	}
+}
+
+func TestCopyTreeConcurrent(t *testing.T) {
+	fsys, client := newTestFs(t)
+	putTree(client, "src", 1000)
+
+	if err := fsys.CopyTreeConcurrent(context.Background(), "src", "dst", 8); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("d%d/file%04d", i%10, i)
+
+		obj, found := client.Object("test", "dst/"+name)
+		if !found {
+			t.Fatalf("dst/%s not found", name)
+		}
+
+		if want := fmt.Sprint(i); string(obj.Data) != want {
+			t.Errorf("dst/%s = %q, want %q", name, obj.Data, want)
+		}
+	}
+
+	if got := client.Count("CopyObject"); got != 1000 {
+		t.Errorf("CopyObject calls = %d, want 1000", got)
+	}
+}
+
+func TestCopyTreeConcurrentResume(t *testing.T) {
+	fsys, client := newTestFs(t)
+	putTree(client, "src", 100)
+	for i := 0; i < 50; i++ {
+		client.Put("test", fmt.Sprintf("dst/d%d/file%04d", i%10, i), []byte(fmt.Sprint(i)))
+	}
+	// same size, different content
+	client.Put("test", "dst/d0/file0000", []byte("x"))
+
+	if err := fsys.CopyTreeConcurrent(context.Background(), "src", "dst", 4); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Count("CopyObject"); got != 51 {
+		t.Errorf("CopyObject calls = %d, want 51", got)
+	}
+
+	if obj, _ := client.Object("test", "dst/d0/file0000"); string(obj.Data) != "0" {
+		t.Errorf("dst/d0/file0000 = %q, want %q", obj.Data, "0")
+	}
+}
+
+// failingCopyClient fails the copies of keys containing "fail".
+type failingCopyClient struct {
+	*s3fstest.Client
+}
+
+func (c failingCopyClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if strings.Contains(aws.ToString(in.Key), "fail") {
+		return nil, s3fstest.Error("CopyObject", http.StatusForbidden, &types.NoSuchKey{})
+	}
+	return c.Client.CopyObject(ctx, in, optFns...)
+}
+
+func TestCopyTreeConcurrentErrors(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "src/fail1", nil)
+	client.Put("test", "src/ok", []byte("ok"))
+	client.Put("test", "src/fail2", nil)
+	fsys := New(failingCopyClient{client}, "test")
+
+	err := fsys.CopyTreeConcurrent(context.Background(), "src", "dst", 1)
+
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		t.Fatalf("CopyTreeConcurrent() error = %v, want a *fs.PathError", err)
+	}
+
+	for _, name := range []string{"src/fail1", "src/fail2"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("CopyTreeConcurrent() error = %v, want %s", err, name)
+		}
+	}
+
+	if obj, found := client.Object("test", "dst/ok"); !found || !bytes.Equal(obj.Data, []byte("ok")) {
+		t.Errorf("dst/ok = %q, %v, want %q", obj.Data, found, "ok")
+	}
+
+	if err := fsys.CopyTreeConcurrent(context.Background(), "missing", "dst", 1); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("CopyTreeConcurrent(missing) error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+// largeListingClient lists the objects as larger than the CopyObject limit,
+// and accepts the multipart copy of them without copying the content.
+type largeListingClient struct {
+	*s3fstest.Client
+	partCopies int
+}
+
+func (c *largeListingClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	out, err := c.Client.ListObjectsV2(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out.Contents {
+		out.Contents[i].Size = aws.Int64(MaxPartSize + 1)
+	}
+	return out, nil
+}
+
+func (c *largeListingClient) UploadPartCopy(context.Context, *s3.UploadPartCopyInput, ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	c.partCopies++
+	return &s3.UploadPartCopyOutput{CopyPartResult: &types.CopyPartResult{ETag: aws.String(`"part"`)}}, nil
+}
+
+func (c *largeListingClient) CompleteMultipartUpload(context.Context, *s3.CompleteMultipartUploadInput, ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func TestCopyTreeConcurrentLarge(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "src/big", []byte("content"))
+	large := &largeListingClient{Client: client}
+	fsys := New(large, "test")
+
+	if err := fsys.CopyTreeConcurrent(context.Background(), "src", "dst", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Count("CopyObject"); got != 0 {
+		t.Errorf("CopyObject calls = %d, want 0", got)
+	}
+
+	if large.partCopies == 0 {
+		t.Error("UploadPartCopy calls = 0, want a multipart copy")
+	}
+}
+
+// cancelingCopyClient cancels the context of the copy once an object is copied.
+type cancelingCopyClient struct {
+	*s3fstest.Client
+	cancel context.CancelFunc
+}
+
+func (c cancelingCopyClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	defer c.cancel()
+	return c.Client.CopyObject(ctx, in, optFns...)
+}
+
+func TestCopyTreeConcurrentCancel(t *testing.T) {
+	client := s3fstest.New()
+	putTree(client, "src", 1000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fsys := New(cancelingCopyClient{Client: client, cancel: cancel}, "test")
+
+	err := fsys.CopyTreeConcurrent(ctx, "src", "dst", 4)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CopyTreeConcurrent() error = %v, want %v", err, context.Canceled)
+	}
+
+	// the workers finish the keys they were handed, and one more may race the cancellation
+	if got := client.Count("CopyObject"); got > 5 {
+		t.Errorf("CopyObject calls = %d, want at most 5", got)
+	}
+}
+
+func TestCopyDirEntry(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+	client.Put("test", "prefix/dir/file", []byte("content"))
+	client.Put("test", "prefix/dir/sub/file", []byte("content"))
+
+	entries, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.Reset()
+
+	for _, entry := range entries {
+		err := fsys.CopyDirEntry(entry, "copy/"+entry.Name())
+		if entry.IsDir() {
+			if !errors.Is(err, ErrIsDirectory) {
+				t.Errorf("CopyDirEntry(%s) error = %v, want %v", entry.Name(), err, ErrIsDirectory)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ops []string
+	for _, call := range client.Calls() {
+		ops = append(ops, call.Op)
+	}
+	if fmt.Sprint(ops) != "[CopyObject]" {
+		t.Errorf("calls = %v, want [CopyObject]", ops)
+	}
+
+	if obj, _ := client.Object("test", "prefix/copy/file"); string(obj.Data) != "content" {
+		t.Errorf("content = %q, want %q", obj.Data, "content")
+	}
+
+	if err := fsys.CopyDirEntry(fs.FileInfoToDirEntry(&FileInfo{name: "file"}), "copy/other"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("CopyDirEntry() error = %v, want %v", err, fs.ErrInvalid)
+	}
+}
+
+func TestCopyParts(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+
+	data := bytes.Repeat([]byte("0123456789"), 1_200_000)
+	client.Put("test", "prefix/src", data)
+
+	if err := fsys.copyParts(context.Background(), "prefix/src", "dst", int64(len(data)), MinPartSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Count("UploadPartCopy"); got != 3 {
+		t.Errorf("UploadPartCopy calls = %d, want 3", got)
+	}
+
+	if obj, _ := client.Object("test", "prefix/dst"); !bytes.Equal(obj.Data, data) {
+		t.Errorf("content differs, size = %d, want %d", len(obj.Data), len(data))
+	}
+}
+
+func TestCopyPartsHeaders(t *testing.T) {
+	fsys, client := newTestFs(t)
+
+	data := bytes.Repeat([]byte("0123456789"), 1_200_000)
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:          aws.String("test"),
+		Key:             aws.String("src"),
+		Body:            bytes.NewReader(data),
+		ContentType:     aws.String("video/mp4"),
+		ContentEncoding: aws.String("identity"),
+		ContentLanguage: aws.String("pt"),
+		Metadata:        map[string]string{"owner": "me"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.copyParts(context.Background(), "src", "dst", int64(len(data)), MinPartSize); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, _ := client.Object("test", "dst")
+	if aws.ToString(obj.ContentType) != "video/mp4" || aws.ToString(obj.ContentEncoding) != "identity" ||
+		aws.ToString(obj.ContentLanguage) != "pt" || obj.Metadata["owner"] != "me" {
+		t.Errorf("copy headers = (%q, %q, %q, %v), want the ones of the source",
+			aws.ToString(obj.ContentType), aws.ToString(obj.ContentEncoding), aws.ToString(obj.ContentLanguage), obj.Metadata)
+	}
+}
+
+// failingCompleteClient fails every CompleteMultipartUpload.
+type failingCompleteClient struct {
+	*s3fstest.Client
+}
+
+func (c failingCompleteClient) CompleteMultipartUpload(context.Context, *s3.CompleteMultipartUploadInput, ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return nil, s3fstest.Error("CompleteMultipartUpload", http.StatusInternalServerError, &smithy.GenericAPIError{Code: "InternalError"})
+}
+
+func TestCopyPartsAbort(t *testing.T) {
+	client := s3fstest.New()
+	client.CreateBucket("test")
+	fsys := New(failingCompleteClient{client}, "test")
+
+	data := bytes.Repeat([]byte("0123456789"), 1_200_000)
+	client.Put("test", "src", data)
+
+	if err := fsys.copyParts(context.Background(), "src", "dst", int64(len(data)), MinPartSize); err == nil {
+		t.Fatal("copyParts() error = nil, want the CompleteMultipartUpload failure")
+	}
+
+	if got := client.Count("AbortMultipartUpload"); got != 1 {
+		t.Errorf("AbortMultipartUpload calls = %d, want 1", got)
+	}
+
+	if _, found := client.Object("test", "dst"); found {
+		t.Error("object dst was created")
+	}
+}
+
+// emptyPartCopyClient replies to UploadPartCopy without a CopyPartResult.
+type emptyPartCopyClient struct {
+	*s3fstest.Client
+}
+
+func (c emptyPartCopyClient) UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	if _, err := c.Client.UploadPartCopy(ctx, in, optFns...); err != nil {
+		return nil, err
+	}
+	return &s3.UploadPartCopyOutput{}, nil
+}
+
+func TestCopyPartsMissingResult(t *testing.T) {
+	client := s3fstest.New()
+	client.CreateBucket("test")
+	fsys := New(emptyPartCopyClient{client}, "test")
+
+	data := bytes.Repeat([]byte("0123456789"), 1_200_000)
+	client.Put("test", "src", data)
+
+	if err := fsys.copyParts(context.Background(), "src", "dst", int64(len(data)), MinPartSize); err == nil {
+		t.Fatal("copyParts() error = nil, want the missing copy result")
+	}
+
+	if got := client.Count("AbortMultipartUpload"); got != 1 {
+		t.Errorf("AbortMultipartUpload calls = %d, want 1", got)
+	}
+
+	if _, found := client.Object("test", "dst"); found {
+		t.Error("object dst was created")
+	}
+}
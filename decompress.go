@@ -0,0 +1,94 @@
+package s3fs
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// WithAutoDecompress decompresses the objects stored with a gzip Content-Encoding
+// read with Open and OpenReader, including the ones fetched from CloudFront.
+// Open checks the encoding with a HeadObject, and returns a file without ReadAt nor Seek
+// for compressed objects, whose Stat reports the compressed size.
+func WithAutoDecompress() Option {
+	return func(f *Fs) {
+		f.autoDecompress = true
+	}
+}
+
+// isGzip reports whether the Content-Encoding is gzip.
+func isGzip(contentEncoding string) bool {
+	return strings.EqualFold(strings.TrimSpace(contentEncoding), "gzip")
+}
+
+// openDecompressed returns file decompressed when the named object is gzip encoded.
+func (f *Fs) openDecompressed(ctx context.Context, name string, file *File) (fs.File, error) {
+	headers, err := f.HeadersWithContext(ctx, name)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if !isGzip(headers.ContentEncoding) {
+		return file, nil
+	}
+
+	return &gzipFile{file: file}, nil
+}
+
+// gzipFile reads the decompressed content of a gzip encoded file.
+type gzipFile struct {
+	file *File
+	gz   *gzip.Reader
+}
+
+func (f *gzipFile) Stat() (fs.FileInfo, error) { return f.file.Stat() }
+
+func (f *gzipFile) Read(p []byte) (int, error) {
+	// the header is only read once the content is, as the object download
+	if f.gz == nil {
+		gz, err := gzip.NewReader(f.file)
+		if err != nil {
+			return 0, err
+		}
+		f.gz = gz
+	}
+
+	return f.gz.Read(p)
+}
+
+func (f *gzipFile) Close() error {
+	if f.gz != nil {
+		_ = f.gz.Close()
+	}
+
+	return f.file.Close()
+}
+
+// gzipReadCloser reads the decompressed content of body, closing body on Close.
+type gzipReadCloser struct {
+	body io.ReadCloser
+	gz   *gzip.Reader
+}
+
+func (r *gzipReadCloser) Read(p []byte) (int, error) {
+	if r.gz == nil {
+		gz, err := gzip.NewReader(r.body)
+		if err != nil {
+			return 0, err
+		}
+		r.gz = gz
+	}
+
+	return r.gz.Read(p)
+}
+
+func (r *gzipReadCloser) Close() error {
+	if r.gz != nil {
+		_ = r.gz.Close()
+	}
+
+	return r.body.Close()
+}
@@ -0,0 +1,108 @@
+package s3fs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestAutoDecompress(t *testing.T) {
+	plain := bytes.Repeat([]byte("plain text "), 1000)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []byte
+	}{
+		{name: "decompressed", opts: []Option{WithAutoDecompress()}, want: plain},
+		{name: "as stored", want: compressed.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t, tt.opts...)
+
+			_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+				Bucket:          aws.String("test"),
+				Key:             aws.String("file.txt"),
+				Body:            bytes.NewReader(compressed.Bytes()),
+				ContentEncoding: aws.String("gzip"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.Put("test", "other.txt", plain)
+
+			f, err := fsys.Open("file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Open() content = %.16q, want %.16q", got, tt.want)
+			}
+
+			// the size is the one of the object
+			info, err := f.Stat()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Size() != int64(compressed.Len()) {
+				t.Errorf("Size() = %d, want %d", info.Size(), compressed.Len())
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := fsys.OpenReader("file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err = io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("OpenReader() content = %.16q, want %.16q", got, tt.want)
+			}
+
+			// objects without encoding are read as is
+			other, err := fsys.Open("other.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = other.Close() }()
+
+			if _, ok := other.(*File); !ok {
+				t.Errorf("Open() = %T, want *File", other)
+			}
+		})
+	}
+}
@@ -1,17 +1,37 @@
 package s3fs
 
 import (
+	"context"
+	"io"
 	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 var (
-	_ fs.File     = (*Directory)(nil)
-	_ fs.DirEntry = (*Directory)(nil)
+	_ fs.File        = (*Directory)(nil)
+	_ fs.DirEntry    = (*Directory)(nil)
+	_ fs.ReadDirFile = (*Directory)(nil)
 )
 
 type Directory struct {
-	fs       *Fs
+	// ctx is the context given when the directory was opened, used by ReadDir.
+	ctx context.Context
+	fs  *Fs
+	// lister and pending hold the ReadDir position across calls.
+	lister  *dirLister
+	pending []fs.DirEntry
+	// name is the path of the directory relative to the Fs.
+	name string
+	// marker is the key of the directory file.
+	marker   string
 	fileInfo FileInfo
+	// hasMarker reports whether the directory file is known to exist
+	hasMarker bool
 }
 
 func (d *Directory) Name() string               { return d.fileInfo.Name() }
@@ -21,6 +41,286 @@ func (d *Directory) Info() (fs.FileInfo, error) { return d.fileInfo.Info() }
 func (d *Directory) Stat() (fs.FileInfo, error) { return &d.fileInfo, nil }
 func (d *Directory) Close() error               { return nil }
 
+// setListed takes the modification time from what lister has seen of the directory:
+// the time of its directory file, or else of its newest file.
+// Until then the directory keeps the time it was opened or listed.
+func (d *Directory) setListed(lister *dirLister) {
+	if lister.marker != nil {
+		d.hasMarker = true
+	}
+
+	if modTime, ok := lister.modTime(); ok {
+		d.fileInfo.modTime = modTime
+	}
+}
+
+// PlaceholderKey returns the key of the directory file backing the directory.
+// It returns false for directories implied by the keys under their prefix.
+// Unless the directory file was seen when listing the directory, it is looked up with a HeadObject request.
+func (d *Directory) PlaceholderKey() (string, bool) {
+	if d.hasMarker || d.marker == "" {
+		return d.marker, d.hasMarker
+	}
+
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if d.fs.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, d.fs.timeout)
+		defer cancelFn()
+	}
+
+	_, err := d.fs.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(d.fs.bucket),
+		Key:    aws.String(d.marker),
+	})
+	if err != nil {
+		return "", false
+	}
+
+	d.hasMarker = true
+
+	return d.marker, true
+}
+
 func (d *Directory) Read(_ []byte) (int, error) {
-	return 0, &fs.PathError{Op: "read", Path: d.fileInfo.Name(), Err: fs.ErrInvalid}
+	return 0, &fs.PathError{Op: "read", Path: d.fileInfo.Name(), Err: ErrIsDirectory}
+}
+
+// ReadDir reads the contents of the directory, continuing from the previous call.
+// If n > 0, it returns at most n entries, and io.EOF once the directory is exhausted.
+// If n <= 0, it returns all the remaining entries.
+func (d *Directory) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.lister == nil {
+		d.lister = newDirLister(d.fs, d.name)
+	}
+
+	for (n <= 0 || len(d.pending) < n) && d.lister.hasMorePages() {
+		entries, err := d.nextPage()
+		if err != nil {
+			return nil, err
+		}
+		d.pending = append(d.pending, entries...)
+	}
+
+	if n <= 0 {
+		entries := d.pending
+		d.pending = nil
+		if entries == nil {
+			entries = []fs.DirEntry{}
+		}
+		return entries, nil
+	}
+
+	if len(d.pending) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(d.pending))
+	entries := d.pending[:n:n]
+	d.pending = d.pending[n:]
+
+	return entries, nil
+}
+
+// Readdir reads the contents of the directory as os.File.Readdir, continuing from the previous call.
+// If n > 0, it returns at most n entries, and io.EOF once the directory is exhausted.
+// If n <= 0, it returns all the remaining entries.
+func (d *Directory) Readdir(n int) ([]fs.FileInfo, error) {
+	entries, err := d.ReadDir(n)
+
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return infos, infoErr
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, err
+}
+
+// Readdirnames reads the names of the directory entries as os.File.Readdirnames,
+// continuing from the previous call.
+// If n > 0, it returns at most n names, and io.EOF once the directory is exhausted.
+// If n <= 0, it returns all the remaining names.
+func (d *Directory) Readdirnames(n int) ([]string, error) {
+	entries, err := d.ReadDir(n)
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names, err
+}
+
+func (d *Directory) nextPage() ([]fs.DirEntry, error) {
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if d.fs.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, d.fs.timeout)
+		defer cancelFn()
+	}
+
+	entries, err := d.lister.nextPage(ctx)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	d.setListed(d.lister)
+
+	return entries, nil
+}
+
+// dirLister lists the entries of a directory a page at a time.
+type dirLister struct {
+	fs        *Fs
+	paginator *s3.ListObjectsV2Paginator
+	// marker is the modification time of the directory file, once listed.
+	marker *time.Time
+	// newest is the latest modification time of the files listed so far.
+	newest       *time.Time
+	seenPrefixes map[string]struct{}
+	dirName      string
+	markerKey    string
+	// prefix is the listed prefix, which raw keys are named relative to.
+	prefix string
+}
+
+func newDirLister(f *Fs, dirName string) *dirLister {
+	opts := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(f.bucket),
+		Prefix:    aws.String(f.withPrefix(dirName) + pathSeparator),
+		Delimiter: aws.String(pathSeparator),
+		MaxKeys:   f.pageSize(),
+	}
+
+	// the root of an Fs without prefix lists the whole bucket
+	if f.withPrefix(dirName) == "" {
+		opts.Prefix = nil
+	}
+
+	return &dirLister{
+		fs:        f,
+		dirName:   dirName,
+		markerKey: f.withPrefix(dirName, f.directoryFile),
+		prefix:    aws.ToString(opts.Prefix),
+		paginator: s3.NewListObjectsV2Paginator(f.client, opts),
+		seenPrefixes: map[string]struct{}{
+			currentDirName: {},
+			pathSeparator:  {},
+		},
+	}
+}
+
+// modTime returns the modification time of the listed directory,
+// the one of its directory file, or else of its newest file, once listed.
+func (l *dirLister) modTime() (time.Time, bool) {
+	switch {
+	case l.marker != nil:
+		return *l.marker, true
+	case l.newest != nil:
+		return *l.newest, true
+	}
+
+	return time.Time{}, false
+}
+
+// entryName returns the name in the listed directory of a key or common prefix.
+// Raw keys are named by what follows the listed prefix instead of being cleaned,
+// so the "a//" prefix has no name in the directory "a", it is listed by the directory "a/".
+func (l *dirLister) entryName(key string) (string, fs.FileMode) {
+	if !l.fs.rawKeys {
+		return baseName(key)
+	}
+
+	name := strings.TrimPrefix(key, l.prefix)
+	if dir, found := strings.CutSuffix(name, pathSeparator); found {
+		return dir, fs.ModeDir
+	}
+
+	return name, 0
+}
+
+func (l *dirLister) hasMorePages() bool {
+	return l.paginator.HasMorePages()
+}
+
+// nextPage returns the entries of the next page sorted by filename.
+func (l *dirLister) nextPage(ctx context.Context) ([]fs.DirEntry, error) {
+	page, err := l.paginator.NextPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []fs.DirEntry
+
+	for _, p := range page.CommonPrefixes {
+		if p.Prefix == nil {
+			continue
+		}
+
+		dir, _ := l.entryName(*p.Prefix)
+		if dir == "" {
+			continue
+		}
+
+		if _, found := l.seenPrefixes[dir]; found {
+			continue
+		}
+
+		l.seenPrefixes[dir] = struct{}{}
+
+		result = append(result, &Directory{
+			fs:       l.fs,
+			name:     l.fs.child(l.dirName, dir),
+			fileInfo: directoryFileInfo(dir, l.fs.now()),
+			marker:   *p.Prefix + l.fs.directoryFile,
+		})
+	}
+
+	for _, obj := range page.Contents {
+		if obj.Key == nil {
+			continue
+		}
+
+		name, mode := l.entryName(*obj.Key)
+		if *obj.Key == l.markerKey {
+			l.marker = aws.Time(getOrElse(obj.LastModified, l.fs.now))
+		}
+		if obj.LastModified != nil && (l.newest == nil || obj.LastModified.After(*l.newest)) {
+			l.newest = obj.LastModified
+		}
+
+		if name == "" || name == pathSeparator || name == l.fs.directoryFile {
+			continue
+		}
+
+		// a directory and a file of the same name resolve to the directory
+		if _, found := l.seenPrefixes[name]; found {
+			continue
+		}
+
+		if mode&fs.ModeDir != 0 {
+			l.seenPrefixes[name] = struct{}{}
+		}
+
+		result = append(result, &File{
+			fs:   l.fs,
+			info: objectFileInfo(name, obj, l.fs.now),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+
+	return result, nil
 }
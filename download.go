@@ -0,0 +1,160 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DownloadOption configures DownloadTo.
+type DownloadOption func(*downloadOptions)
+
+type downloadOptions struct {
+	concurrency int
+}
+
+// WithDownloadConcurrency sets the number of parts downloaded at once by DownloadTo,
+// defaults to manager.DefaultDownloadConcurrency.
+func WithDownloadConcurrency(concurrency int) DownloadOption {
+	return func(o *downloadOptions) {
+		if concurrency > 0 {
+			o.concurrency = concurrency
+		}
+	}
+}
+
+// DownloadTo writes the named object to w, returning the number of bytes written.
+// The parts are downloaded concurrently and written at their offset in w, such as an *os.File,
+// instead of being streamed in order as when reading a File.
+func (f *Fs) DownloadTo(ctx context.Context, name string, w io.WriterAt, opts ...DownloadOption) (int64, error) {
+	o := downloadOptions{concurrency: manager.DefaultDownloadConcurrency}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	downloader := manager.NewDownloader(f.client, func(d *manager.Downloader) {
+		d.Concurrency = o.concurrency
+		d.PartSize = f.downloadPartSize
+	})
+
+	n, err := downloader.Download(ctx, f.limitWriterAt(ctx, w), &s3.GetObjectInput{
+		Bucket:       aws.String(f.bucket),
+		Key:          aws.String(f.withPrefix(name)),
+		ChecksumMode: f.checksumMode,
+	})
+	f.addBytes(BytesRead, int(n))
+	if err != nil {
+		switch {
+		case isNotFound(err):
+			return n, &fs.PathError{Op: "download", Path: name, Err: fs.ErrNotExist}
+		case httpStatusCode(err) == http.StatusRequestedRangeNotSatisfiable:
+			// some endpoints reply to ranged requests on empty objects
+			// with 416 Range Not Satisfiable instead of an empty body
+			return n, nil
+		}
+		return n, mapError(err)
+	}
+
+	return n, nil
+}
+
+// DownloadResumable downloads the named object to the local file at localPath,
+// resuming an interrupted download: the bytes already in the local file are not fetched again,
+// the remaining ones are requested from its size onwards and appended.
+// The ETag of the object is kept in a localPath.etag file until the download completes:
+// when the object changed since the download started, ErrPreconditionFailed is returned,
+// and fs.ErrInvalid is returned when the local file is larger than the object.
+func (f *Fs) DownloadResumable(ctx context.Context, name, localPath string) error {
+	head, err := f.headKey(ctx, f.withPrefix(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &fs.PathError{Op: "download", Path: name, Err: fs.ErrNotExist}
+		}
+		return err
+	}
+	size := aws.ToInt64(head.ContentLength)
+
+	dst, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	info, err := dst.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+
+	etagPath := localPath + ".etag"
+	etag := aws.ToString(head.ETag)
+	if offset == 0 {
+		if err := os.WriteFile(etagPath, []byte(etag), 0o644); err != nil {
+			return err
+		}
+	} else {
+		// a local file without the ETag of its first attempt is taken as of the current object
+		started, err := os.ReadFile(etagPath)
+		switch {
+		case err == nil && string(started) != etag:
+			return &fs.PathError{Op: "download", Path: name, Err: ErrPreconditionFailed}
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+	}
+
+	switch {
+	case offset > size:
+		return &fs.PathError{Op: "download", Path: localPath, Err: fs.ErrInvalid}
+	case offset == size:
+		return completeResumable(dst, etagPath)
+	}
+
+	res, err := f.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:       aws.String(f.bucket),
+		Key:          aws.String(f.withPrefix(name)),
+		Range:        aws.String(fmt.Sprintf("bytes=%d-", offset)),
+		IfMatch:      head.ETag,
+		ChecksumMode: f.checksumMode,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return &fs.PathError{Op: "download", Path: name, Err: fs.ErrNotExist}
+		}
+		return mapError(err)
+	}
+	defer res.Body.Close()
+
+	n, err := io.Copy(dst, f.limitReader(ctx, res.Body))
+	f.addBytes(BytesRead, int(n))
+	if err != nil {
+		return err
+	}
+
+	if offset+n != size {
+		return &fs.PathError{Op: "download", Path: name, Err: io.ErrUnexpectedEOF}
+	}
+
+	return completeResumable(dst, etagPath)
+}
+
+// completeResumable closes the downloaded file and removes the ETag kept for resuming it.
+func completeResumable(dst *os.File, etagPath string) error {
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(etagPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func TestDownloadTo(t *testing.T) {
+	const size = 64 * 1024 * 1024
+
+	fsys, client := newTestFs(t)
+
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	client.Put("test", "large.bin", data)
+
+	dst, err := os.Create(filepath.Join(t.TempDir(), "large.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	n, err := fsys.DownloadTo(context.Background(), "large.bin", dst, WithDownloadConcurrency(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != size {
+		t.Errorf("DownloadTo() = %d, want %d", n, size)
+	}
+
+	if _, err := dst.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sha256.Sum256(data)
+	if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+		t.Errorf("checksum = %x, want %x", got, want)
+	}
+
+	if got := client.Count("GetObject"); got < 2 {
+		t.Errorf("GetObject calls = %d, want a multipart download", got)
+	}
+}
+
+func TestDownloadToMetrics(t *testing.T) {
+	recorder := newFakeRecorder()
+	fsys, client := newTestFs(t, WithMetrics(recorder))
+	client.Put("test", "file", []byte("content"))
+
+	dst, err := os.Create(filepath.Join(t.TempDir(), "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	if _, err := fsys.DownloadTo(context.Background(), "file", dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := recorder.bytes[BytesRead]; got != 7 {
+		t.Errorf("AddBytes(%s) = %d, want 7", BytesRead, got)
+	}
+}
+
+func TestDownloadToNotExist(t *testing.T) {
+	fsys, _ := newTestFs(t)
+
+	dst, err := os.Create(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	if _, err := fsys.DownloadTo(context.Background(), "missing", dst); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("DownloadTo() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestDownloadResumable(t *testing.T) {
+	const size = 1024 * 1024
+
+	fsys, client := newTestFs(t)
+
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	client.Put("test", "file.bin", data)
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	if err := fsys.DownloadResumable(context.Background(), "file.bin", dst); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulates an interrupted download
+	if err := os.Truncate(dst, size/2); err != nil {
+		t.Fatal(err)
+	}
+
+	client.Reset()
+
+	if err := fsys.DownloadResumable(context.Background(), "file.bin", dst); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sha256.Sum256(got) != sha256.Sum256(data) {
+		t.Errorf("checksum mismatch, size = %d, want %d", len(got), size)
+	}
+
+	calls := client.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("calls = %d, want HeadObject and GetObject", len(calls))
+	}
+
+	in, ok := calls[1].Input.(*s3.GetObjectInput)
+	if !ok {
+		t.Fatalf("call = %T, want GetObject", calls[1].Input)
+	}
+
+	if got, want := aws.ToString(in.Range), "bytes=524288-"; got != want {
+		t.Errorf("GetObject Range = %q, want %q", got, want)
+	}
+
+	// a complete download is not fetched again
+	client.Reset()
+
+	if err := fsys.DownloadResumable(context.Background(), "file.bin", dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Count("GetObject"); got != 0 {
+		t.Errorf("GetObject calls = %d, want 0", got)
+	}
+}
+
+func TestDownloadResumableMetrics(t *testing.T) {
+	recorder := newFakeRecorder()
+	fsys, client := newTestFs(t, WithMetrics(recorder))
+	client.Put("test", "file.bin", []byte("0123456789"))
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(dst, []byte("0123"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.DownloadResumable(context.Background(), "file.bin", dst); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the bytes after the local file are downloaded
+	if got := recorder.bytes[BytesRead]; got != 6 {
+		t.Errorf("AddBytes(%s) = %d, want 6", BytesRead, got)
+	}
+}
+
+func TestDownloadResumableErrors(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file.txt", []byte("content"))
+
+	dst := filepath.Join(t.TempDir(), "file.txt")
+
+	if err := fsys.DownloadResumable(context.Background(), "missing", dst); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("DownloadResumable() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if err := os.WriteFile(dst, []byte("content and more"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.DownloadResumable(context.Background(), "file.txt", dst); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("DownloadResumable() error = %v, want %v", err, fs.ErrInvalid)
+	}
+}
+
+// truncatingClient ends the body of every GetObject after n bytes, as an interrupted download.
+type truncatingClient struct {
+	*s3fstest.Client
+	n int64
+}
+
+func (c truncatingClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	out, err := c.Client.GetObject(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Body = io.NopCloser(io.LimitReader(out.Body, c.n))
+	return out, nil
+}
+
+func TestDownloadResumableReplaced(t *testing.T) {
+	tests := []struct {
+		wantErr error
+		name    string
+		replace bool
+	}{
+		{name: "unchanged"},
+		{name: "replaced", replace: true, wantErr: ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+
+			data := bytes.Repeat([]byte("a"), 64*1024)
+			client.Put("test", "file.bin", data)
+
+			dst := filepath.Join(t.TempDir(), "file.bin")
+			err := New(truncatingClient{client, 1024}, "test").DownloadResumable(context.Background(), "file.bin", dst)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("DownloadResumable() error = %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+
+			if tt.replace {
+				client.Put("test", "file.bin", bytes.Repeat([]byte("b"), len(data)))
+			}
+
+			err = fsys.DownloadResumable(context.Background(), "file.bin", dst)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DownloadResumable() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			got, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, data) {
+				t.Errorf("content mismatch, size = %d, want %d", len(got), len(data))
+			}
+
+			if _, err := os.Stat(dst + ".etag"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat(%s.etag) error = %v, want %v", dst, err, fs.ErrNotExist)
+			}
+		})
+	}
+}
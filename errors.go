@@ -0,0 +1,137 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// The file system errors wrap the io/fs errors they refine,
+// so errors.Is matches both the sentinel and its io/fs counterpart:
+//
+//	ErrNotDirectory  fs.ErrInvalid  listing or opening a file as a directory
+//	ErrIsDirectory   fs.ErrInvalid  reading, writing or removing a directory as a file
+//	ErrNotEmpty      fs.ErrInvalid  removing a directory with entries
+//	ErrInvalidKey    fs.ErrInvalid  writing a key rejected by WithSanitizeKeys
+//	ErrInvalidPrefix fs.ErrInvalid  using a WithPrefix naming an object
+//
+// Creating a file over a directory also matches fs.ErrExist.
+var (
+	// ErrBucketNotFound is returned when the bucket does not exist.
+	ErrBucketNotFound = errors.New("bucket not found")
+	// ErrPreconditionFailed is returned when a conditional write does not hold.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrBadDigest is returned when the written object does not match its Content-MD5.
+	ErrBadDigest = errors.New("bad digest")
+	// ErrChecksumMismatch is returned when the content read does not match the object checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrNoChecksum is returned when the object has no checksum of the requested algorithm.
+	ErrNoChecksum = errors.New("no checksum")
+	// ErrRenameCleanupFailed is returned by Rename when the object was copied to the new path
+	// but the old path could not be deleted, so both exist and the delete can be retried.
+	ErrRenameCleanupFailed = errors.New("rename cleanup failed")
+	// ErrIncompleteListing is returned by ReadDir with WithPartialListing,
+	// along with the entries listed before a page failed.
+	ErrIncompleteListing = errors.New("incomplete listing")
+	// ErrListingTruncated is returned by ReadDir, ReadFiles, ReadDirFlat, RangeDir and WalkDir
+	// along with the entries of the first pages when the listing has more than WithMaxListPages.
+	ErrListingTruncated = errors.New("listing truncated")
+	// ErrInvalidConfig is returned by Validate when the options are inconsistent.
+	ErrInvalidConfig = errors.New("invalid configuration")
+	// ErrInvalidKey is returned when writing a key rejected by WithSanitizeKeys,
+	// it matches fs.ErrInvalid.
+	ErrInvalidKey = fmt.Errorf("invalid key: %w", fs.ErrInvalid)
+	// ErrInvalidPart is returned by ReadPart for a part the object does not have,
+	// it matches fs.ErrInvalid.
+	ErrInvalidPart = fmt.Errorf("invalid part number: %w", fs.ErrInvalid)
+	// ErrInvalidPrefix is returned when the prefix set by WithPrefix is the key of an object,
+	// as it must name a directory, it matches fs.ErrInvalid.
+	ErrInvalidPrefix = fmt.Errorf("prefix is an object, not a directory: %w", fs.ErrInvalid)
+	// ErrNotDirectory is returned when a file is used as a directory,
+	// it matches fs.ErrInvalid.
+	ErrNotDirectory = fmt.Errorf("not a directory: %w", fs.ErrInvalid)
+	// ErrIsDirectory is returned when a directory is used as a file,
+	// it matches fs.ErrInvalid.
+	ErrIsDirectory = fmt.Errorf("is a directory: %w", fs.ErrInvalid)
+	// ErrNotEmpty is returned when removing a directory with entries,
+	// it matches fs.ErrInvalid.
+	ErrNotEmpty = fmt.Errorf("directory not empty: %w", fs.ErrInvalid)
+	// ErrDirectoryNotEmpty is returned when removing a directory with entries.
+	//
+	// Deprecated: use ErrNotEmpty.
+	ErrDirectoryNotEmpty = ErrNotEmpty
+)
+
+// BatchDeleteError is returned when some of the keys of a batched delete failed,
+// the other keys being deleted.
+type BatchDeleteError struct {
+	// Failures maps the names which could not be deleted to the S3 error code.
+	Failures map[string]string
+}
+
+func (e *BatchDeleteError) Error() string {
+	names := make([]string, 0, len(e.Failures))
+	for name := range e.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "delete failed for %d keys:", len(names))
+	for _, name := range names {
+		fmt.Fprintf(&b, " %s (%s)", name, e.Failures[name])
+	}
+
+	return b.String()
+}
+
+// mapError translates S3 errors into the package errors,
+// the original error is kept in the chain.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NoSuchBucket":
+			return fmt.Errorf("%w: %w", ErrBucketNotFound, err)
+		case "BadDigest":
+			return fmt.Errorf("%w: %w", ErrBadDigest, err)
+		}
+	}
+
+	if httpStatusCode(err) == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %w", ErrPreconditionFailed, err)
+	}
+
+	return err
+}
+
+// isNotFound reports whether err is a S3 not found response,
+// as returned by HeadObject (which has no error code) or GetObject.
+// A missing bucket is not, so mapError reports it as ErrBucketNotFound.
+func isNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchBucket" {
+		return false
+	}
+
+	return httpStatusCode(err) == http.StatusNotFound
+}
+
+func httpStatusCode(err error) int {
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) {
+		return re.HTTPStatusCode()
+	}
+
+	return 0
+}
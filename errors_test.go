@@ -0,0 +1,60 @@
+package s3fs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file.txt", []byte("content"))
+	client.Put("test", "dir/file.txt", []byte("content"))
+
+	tests := []struct {
+		call func() error
+		name string
+		want []error
+	}{
+		{
+			name: "read dir on file",
+			call: func() error {
+				_, err := fsys.ReadDir("file.txt")
+				return err
+			},
+			want: []error{ErrNotDirectory, fs.ErrInvalid},
+		},
+		{
+			name: "create on dir",
+			call: func() error {
+				_, err := fsys.Create("dir")
+				return err
+			},
+			want: []error{ErrIsDirectory, fs.ErrInvalid, fs.ErrExist},
+		},
+		{
+			name: "remove dir on non empty",
+			call: func() error {
+				return fsys.RemoveDir("dir")
+			},
+			want: []error{ErrNotEmpty, fs.ErrInvalid},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+
+			var pathErr *fs.PathError
+			if !errors.As(err, &pathErr) {
+				t.Errorf("error = %v, want *fs.PathError", err)
+			}
+
+			for _, want := range tt.want {
+				if !errors.Is(err, want) {
+					t.Errorf("error = %v, want errors.Is %v", err, want)
+				}
+			}
+		})
+	}
+}
@@ -2,13 +2,20 @@ package s3fs
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/eikenb/pipeat"
 )
 
@@ -16,30 +23,155 @@ var (
 	_ fs.File        = (*File)(nil)
 	_ fs.DirEntry    = (*File)(nil)
 	_ writerCloserAt = (*File)(nil)
+	_ io.WriterTo    = (*File)(nil)
+	_ io.ReaderFrom  = (*File)(nil)
 )
 
 type File struct {
+	// ctx is the context given when the file was opened,
+	// transfers started afterwards (e.g. on Seek) derive from it.
+	ctx            context.Context
 	reader         readerCloserAt
 	writer         writerCloserAt
 	fs             *Fs
 	readerCancelFn context.CancelFunc
+	// readerDone is closed when the download feeding reader returns.
+	readerDone     chan struct{}
 	writerCancelFn context.CancelFunc
-	info           FileInfo
-	offset         int64
+	writerDone     chan error
+	// closeErr is the result of the first Close, returned by the following ones.
+	closeErr error
+	// uploadErr is the failure of the background upload, guarded by uploadMu.
+	uploadErr error
+	// versionID is the version read, set by OpenVersion.
+	versionID *string
+	// buffer is the local copy of a file opened for reading and writing,
+	// see OpenFile.
+	buffer     *os.File
+	bufferOpts []WriteOption
+	info       FileInfo
+	offset     int64
+	written    atomic.Int64
+	bufferFlag int
+	closeOnce  sync.Once
+	uploadMu   sync.Mutex
+	dirty      bool
+	closed     bool
+}
+
+// WriteOption is a configuration applied to a single write.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	ifMatch         *string
+	ifNoneMatch     *string
+	contentLanguage *string
+	// size is the number of bytes declared by CreateSized
+	size               *int64
+	contentMD5         bool
+	deterministicParts bool
+}
+
+// WithIfMatch only writes the object if its current ETag matches etag,
+// otherwise Close returns ErrPreconditionFailed.
+func WithIfMatch(etag string) WriteOption {
+	return func(o *writeOptions) {
+		o.ifMatch = aws.String(etag)
+	}
+}
+
+// WithIfNoneMatch only writes the object if it does not exist yet,
+// otherwise Close returns ErrPreconditionFailed.
+func WithIfNoneMatch() WriteOption {
+	return func(o *writeOptions) {
+		o.ifNoneMatch = aws.String("*")
+	}
+}
+
+// WithContentMD5 sets the Content-MD5 of objects written in a single part,
+// smaller than the upload part size, so S3 rejects a corrupted body.
+// Close then returns ErrBadDigest. Multipart uploads are not verified.
+func WithContentMD5() WriteOption {
+	return func(o *writeOptions) {
+		o.contentMD5 = true
+	}
+}
+
+// WithDeterministicParts buffers the writes in the temporary directory
+// and uploads parts of exactly the upload part size, except the last,
+// so writing the same content always results in the same multipart ETag.
+// Nothing is uploaded until Close.
+func WithDeterministicParts() WriteOption {
+	return func(o *writeOptions) {
+		o.deterministicParts = true
+	}
+}
+
+// conditionalClient forwards the write conditions to CompleteMultipartUpload,
+// and the Content-MD5 to PutObject, which the uploader does not do on its own.
+type conditionalClient struct {
+	manager.UploadAPIClient
+	fs   *Fs
+	opts writeOptions
+}
+
+func (c conditionalClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	params.IfMatch = c.opts.ifMatch
+	params.IfNoneMatch = c.opts.ifNoneMatch
+	return c.UploadAPIClient.CompleteMultipartUpload(ctx, params, optFns...)
+}
+
+func (c conditionalClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if body, ok := params.Body.(io.ReadSeeker); ok && c.opts.contentMD5 {
+		h := md5.New()
+		if _, err := io.Copy(h, body); err != nil {
+			return nil, err
+		}
+
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		params.ContentMD5 = aws.String(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	}
+
+	// the uploader only sends bodies smaller than a part with PutObject,
+	// which is not a fallback when CreateSized asked for a single part
+	if c.opts.size == nil {
+		c.fs.notice("write", "smaller than the part size, uploaded with PutObject")
+	}
+
+	return c.UploadAPIClient.PutObject(ctx, params, optFns...)
 }
 
 func (f *File) Name() string               { return f.info.Name() }
 func (f *File) IsDir() bool                { return f.info.IsDir() }
 func (f *File) Type() fs.FileMode          { return f.info.Type() }
 func (f *File) Info() (fs.FileInfo, error) { return f.info.Info() }
-func (f *File) Stat() (fs.FileInfo, error) { return &f.info, nil }
+
+func (f *File) Stat() (fs.FileInfo, error) {
+	if f.buffer != nil {
+		info, err := f.buffer.Stat()
+		if err != nil {
+			return nil, err
+		}
+		f.info.size = info.Size()
+	}
+
+	return &f.info, nil
+}
 
 func (f *File) Read(b []byte) (int, error) {
-	if f.reader == nil {
-		return 0, fmt.Errorf("file not open for reading: %w", fs.ErrClosed)
+	if f.buffer != nil {
+		return f.buffer.Read(b)
+	}
+
+	if f.closed || f.reader == nil {
+		return 0, f.errClosed("read")
 	}
 
 	n, err := f.reader.Read(b)
+	f.fs.addBytes(BytesRead, n)
 	if err != nil {
 		return n, err
 	}
@@ -50,15 +182,45 @@ func (f *File) Read(b []byte) (int, error) {
 }
 
 func (f *File) ReadAt(b []byte, offset int64) (int, error) {
-	if f.reader == nil {
-		return 0, fmt.Errorf("file not open for reading: %w", fs.ErrClosed)
+	if f.buffer != nil {
+		return f.buffer.ReadAt(b, offset)
+	}
+
+	if f.closed || f.reader == nil {
+		return 0, f.errClosed("read")
+	}
+	n, err := f.reader.ReadAt(b, offset)
+	f.fs.addBytes(BytesRead, n)
+
+	return n, err
+}
+
+// WriteTo implements io.WriterTo interface,
+// it writes the remaining content of the file to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	if f.buffer != nil {
+		return io.Copy(w, f.buffer)
 	}
-	return f.reader.ReadAt(b, offset)
+
+	if f.closed || f.reader == nil {
+		return 0, f.errClosed("read")
+	}
+
+	// reader only exposes Read, so io.Copy does not call back into File
+	n, err := io.Copy(w, struct{ io.Reader }{f.reader})
+	f.offset += n
+	f.fs.addBytes(BytesRead, int(n))
+
+	return n, err
 }
 
 func (f *File) Seek(offset int64, whence int) (int64, error) {
-	if f.reader == nil {
-		return 0, fmt.Errorf("seek only supported for reading: %w", fs.ErrClosed)
+	if f.buffer != nil {
+		return f.buffer.Seek(offset, whence)
+	}
+
+	if f.closed || f.reader == nil {
+		return 0, f.errClosed("seek")
 	}
 
 	var start int64
@@ -71,14 +233,14 @@ func (f *File) Seek(offset int64, whence int) (int64, error) {
 		start = f.offset + offset
 
 	case io.SeekEnd:
-		start = f.info.Size() - offset
+		start = f.info.Size() + offset
 	}
 
 	if start < 0 || start > f.info.Size() {
 		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
 	}
 
-	return start, f.openReaderAt(context.Background(), start)
+	return start, f.openReaderAt(f.ctx, start)
 }
 
 func (f *File) openReaderAt(ctx context.Context, offset int64) error {
@@ -87,20 +249,36 @@ func (f *File) openReaderAt(ctx context.Context, offset int64) error {
 	}
 
 	if f.reader != nil {
-		if err := f.Close(); err != nil {
+		if err := f.reader.Close(); err != nil {
 			return err
 		}
 	}
 
+	// the download must not outlive the reader it feeds
+	f.waitReader()
+
 	r, w, err := pipeat.PipeInDir(f.fs.tempDir)
 	if err != nil {
 		return err
 	}
 
+	// nothing is left past the end, and S3 rejects the range
+	if offset > 0 && offset >= f.info.Size() {
+		// closing the writer waits for the reader
+		go func() { _ = w.Close() }()
+
+		f.offset = offset
+		f.reader = r
+		f.readerCancelFn = nil
+		f.readerDone = nil
+
+		return nil
+	}
+
 	ctx, cancelFn := context.WithCancel(ctx)
 	downloader := manager.NewDownloader(f.fs.client, func(d *manager.Downloader) {
 		d.Concurrency = 1
-		d.PartSize = f.fs.partSize
+		d.PartSize = f.fs.downloadPartSize
 	})
 
 	var streamRange *string
@@ -108,90 +286,346 @@ func (f *File) openReaderAt(ctx context.Context, offset int64) error {
 		streamRange = aws.String(fmt.Sprintf("bytes=%d-", offset))
 	}
 
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		defer cancelFn()
 
-		_, err := downloader.Download(ctx, w, &s3.GetObjectInput{
-			Bucket: aws.String(f.fs.bucket),
-			Key:    aws.String(f.fs.withPrefix(f.Name())),
-			Range:  streamRange,
+		var dst io.WriterAt = w
+
+		// only a read of the latest version from the start can be compared with the object checksum
+		var checksum *checksumWriterAt
+		if f.fs.checksumMode == types.ChecksumModeEnabled && offset == 0 && f.versionID == nil {
+			var err error
+			if checksum, err = f.fs.checksumWriter(ctx, f.Name(), w); err != nil {
+				_ = w.CloseWithError(mapError(err))
+				return
+			}
+			if checksum != nil {
+				dst = checksum
+			}
+		}
+
+		dst = f.fs.limitWriterAt(ctx, dst)
+
+		_, err := downloader.Download(ctx, dst, &s3.GetObjectInput{
+			Bucket:       aws.String(f.fs.bucket),
+			Key:          aws.String(f.fs.withPrefix(f.Name())),
+			Range:        streamRange,
+			VersionId:    f.versionID,
+			ChecksumMode: f.fs.checksumMode,
 		})
-		_ = w.CloseWithError(err)
+		// some endpoints reply to ranged requests on empty objects
+		// with 416 Range Not Satisfiable instead of an empty body
+		if httpStatusCode(err) == http.StatusRequestedRangeNotSatisfiable {
+			err = nil
+		}
+		if err == nil && checksum != nil {
+			err = checksum.verify(f.Name())
+		}
+		_ = w.CloseWithError(mapError(err))
 	}()
 
 	f.offset = offset
 	f.reader = r
 	f.readerCancelFn = cancelFn
+	f.readerDone = done
 
 	return nil
 }
 
-func (f *File) openWriter(ctx context.Context) error {
+// waitReader waits for the download started by openReaderAt to return.
+// The reader must be closed first, the download only ends once it is.
+func (f *File) waitReader() {
+	if f.readerDone != nil {
+		<-f.readerDone
+		f.readerDone = nil
+	}
+}
+
+func (f *File) openWriter(ctx context.Context, opts ...WriteOption) error {
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	r, w, err := pipeat.PipeInDir(f.fs.tempDir)
 	if err != nil {
 		return err
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
-	uploader := manager.NewUploader(f.fs.client, func(u *manager.Uploader) {
-		u.Concurrency = 1
-		u.PartSize = f.fs.partSize
-	})
+	done := make(chan error, 1)
 
 	go func() {
 		defer cancel()
 
-		_, err := uploader.Upload(ctx, &s3.PutObjectInput{
-			Bucket: aws.String(f.fs.bucket),
-			Key:    aws.String(f.fs.withPrefix(f.Name())),
-			Body:   r,
-		})
+		err := f.upload(ctx, r, o)
+		if err != nil {
+			f.setUploadErr(err)
+		}
+		done <- err
 		_ = r.CloseWithError(err)
 	}()
 
 	f.writer = w
 	f.writerCancelFn = cancel
+	f.writerDone = done
 
 	return nil
 }
 
+// upload uploads body to the file key, in parts of the upload part size.
+func (f *File) upload(ctx context.Context, body io.Reader, o writeOptions) error {
+	uploader := manager.NewUploader(conditionalClient{UploadAPIClient: f.fs.client, fs: f.fs, opts: o}, func(u *manager.Uploader) {
+		u.Concurrency = 1
+		u.PartSize = f.fs.uploadPartSize
+	})
+
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(f.fs.bucket),
+		Key:             aws.String(f.fs.withPrefix(f.Name())),
+		Body:            f.fs.limitReader(ctx, body),
+		ACL:             f.fs.acl,
+		IfMatch:         o.ifMatch,
+		IfNoneMatch:     o.ifNoneMatch,
+		ContentLanguage: o.contentLanguage,
+		ContentType:     f.fs.contentType(f.Name()),
+		ContentLength:   o.size,
+		// the uploader sets it on each part of multipart uploads
+		ChecksumAlgorithm: f.fs.uploadChecksum,
+	})
+
+	return mapError(err)
+}
+
 // Write implements io.Writer interface.
+// Once the background upload failed, such as on AccessDenied, Write returns its error.
 func (f *File) Write(p []byte) (n int, err error) {
-	if f.writer == nil {
-		return 0, fmt.Errorf("file not open for writing: %w", fs.ErrClosed)
+	if f.buffer != nil {
+		if f.bufferFlag&os.O_APPEND != 0 {
+			if _, err := f.buffer.Seek(0, io.SeekEnd); err != nil {
+				return 0, err
+			}
+		}
+		f.dirty = true
+		return f.buffer.Write(p)
+	}
+
+	if f.closed || f.writer == nil {
+		return 0, f.errClosed("write")
 	}
-	return f.writer.Write(p)
+	if err := f.uploadError(nil); err != nil {
+		return 0, err
+	}
+	n, err = f.writer.Write(p)
+	if err != nil {
+		// the pipe is closed after the upload failed, reports why
+		err = f.uploadError(err)
+	}
+	f.written.Add(int64(n))
+	f.fs.addBytes(BytesWritten, n)
+
+	return n, err
+}
+
+// setUploadErr records the failure of the background upload, returned by the following writes.
+func (f *File) setUploadErr(err error) {
+	f.uploadMu.Lock()
+	defer f.uploadMu.Unlock()
+	f.uploadErr = err
+}
+
+// uploadError returns the failure of the background upload if any, err otherwise.
+func (f *File) uploadError(err error) error {
+	f.uploadMu.Lock()
+	defer f.uploadMu.Unlock()
+
+	if f.uploadErr != nil {
+		return f.uploadErr
+	}
+	return err
 }
 
 // WriteAt implements io.WriterAt interface.
 func (f *File) WriteAt(p []byte, off int64) (n int, err error) {
-	if f.writer == nil {
-		return 0, fmt.Errorf("file not open for writing: %w", fs.ErrClosed)
+	if f.buffer != nil {
+		if f.bufferFlag&os.O_APPEND != 0 {
+			return 0, &fs.PathError{Op: "writeat", Path: f.info.name, Err: fs.ErrInvalid}
+		}
+		f.dirty = true
+		return f.buffer.WriteAt(p, off)
+	}
+
+	if f.closed || f.writer == nil {
+		return 0, f.errClosed("write")
+	}
+	if err := f.uploadError(nil); err != nil {
+		return 0, err
+	}
+	n, err = f.writer.WriteAt(p, off)
+	if err != nil {
+		// the pipe is closed after the upload failed, reports why
+		err = f.uploadError(err)
+	}
+	f.written.Add(int64(n))
+	f.fs.addBytes(BytesWritten, n)
+
+	return n, err
+}
+
+// ReadFrom implements io.ReaderFrom interface,
+// it writes the content of r to the file until EOF.
+// A failed upload is returned by the first write following the failure.
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	if f.buffer != nil {
+		if f.bufferFlag&os.O_APPEND != 0 {
+			if _, err := f.buffer.Seek(0, io.SeekEnd); err != nil {
+				return 0, err
+			}
+		}
+		f.dirty = true
+		return io.Copy(f.buffer, r)
+	}
+
+	if f.closed || f.writer == nil {
+		return 0, f.errClosed("write")
+	}
+
+	// hides ReadFrom, so each chunk goes through Write
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+// errClosed is returned by operations on a closed file,
+// or on a file not opened for them.
+func (f *File) errClosed(op string) error {
+	return &fs.PathError{Op: op, Path: f.info.name, Err: fs.ErrClosed}
+}
+
+// Truncate changes the size of the file being written.
+// Since objects are uploaded as a stream, Truncate must be called before any data is written:
+// the upload is seeded with the first size bytes of the existing object and further writes are appended.
+// Truncate(0) replaces the object with an empty body.
+// Growing a file is not supported, except for the local copy of OpenFile.
+func (f *File) Truncate(size int64) error {
+	if f.buffer != nil {
+		f.dirty = true
+		return f.buffer.Truncate(size)
+	}
+
+	if f.closed {
+		return f.errClosed("truncate")
+	}
+
+	if f.writer == nil || f.written.Load() > 0 || size < 0 {
+		return &fs.PathError{Op: "truncate", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+
+	if size == 0 {
+		return nil
+	}
+
+	ctx := f.ctx
+
+	info, err := f.fs.StatWithContext(ctx, f.Name())
+	if err != nil {
+		return err
+	}
+
+	if size > info.Size() {
+		return &fs.PathError{Op: "truncate", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+
+	if f.fs.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.fs.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.fs.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(f.fs.withPrefix(f.Name())),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", size-1)),
+	})
+	if err != nil {
+		return mapError(err)
 	}
-	return f.writer.WriteAt(p, off)
+	defer func() { _ = res.Body.Close() }()
+
+	_, err = io.CopyN(f, res.Body, size)
+	return err
 }
 
 // Close implements io.Closer interface.
+// For a file opened for writing, it waits for the upload and returns its error.
+// Calling Close again returns the result of the first call.
 func (f *File) Close() error {
+	f.closeOnce.Do(func() { f.closeErr = f.close() })
+	return f.closeErr
+}
+
+// close releases the transfers of the file, returning the error of the background upload.
+func (f *File) close() error {
+	f.closed = true
+
+	// flushing the buffer starts a writer, so the functions are only known on return
+	defer func() {
+		if f.readerCancelFn != nil {
+			f.readerCancelFn()
+		}
+		if f.writerCancelFn != nil {
+			f.writerCancelFn()
+		}
+	}()
+
+	if f.buffer != nil {
+		if err := f.flushBuffer(); err != nil {
+			return err
+		}
+	}
+
 	if f.reader != nil {
 		if err := f.reader.Close(); err != nil {
 			return err
 		}
+		if f.readerCancelFn != nil {
+			f.readerCancelFn()
+		}
+		f.waitReader()
 	}
 
-	if f.readerCancelFn != nil {
-		f.readerCancelFn()
+	if f.writer == nil {
+		return nil
 	}
 
-	if f.writer != nil {
-		if err := f.writer.Close(); err != nil {
-			return err
-		}
-	}
+	err := f.writer.Close()
 
-	if f.writerCancelFn != nil {
-		f.writerCancelFn()
+	// the upload error, such as AccessDenied, explains a failed close of the writer
+	if uploadErr := <-f.writerDone; uploadErr != nil {
+		err = uploadErr
+	}
+	f.fs.invalidate(f.Name())
+	if err != nil {
+		return err
 	}
 
+	// the written count is wrong for overlapping WriteAt
+	f.refreshInfo(f.written.Load())
+
 	return nil
 }
+
+// refreshInfo sets the size and time of the file from its stored object once uploaded,
+// since the clock never matches LastModified.
+// Credentials allowed to write only may not read it back, size and the clock are used then.
+func (f *File) refreshInfo(size int64) {
+	head, err := f.fs.headKey(f.ctx, f.fs.withPrefix(f.Name()))
+	if err != nil {
+		f.info.size = size
+		f.info.modTime = f.fs.now()
+		return
+	}
+
+	f.info.size = aws.ToInt64(head.ContentLength)
+	f.info.modTime = aws.ToTime(head.LastModified)
+}
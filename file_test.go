@@ -0,0 +1,968 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func TestFileDownloadPartSize(t *testing.T) {
+	fsys, client := newTestFs(t, WithPartSize(7*1024*1024), WithDownloadPartSize(6*1024*1024))
+	data := bytes.Repeat([]byte("a"), 13*1024*1024)
+	client.Put("test", "file", data)
+
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Fatalf("content mismatch")
+	}
+
+	for _, call := range client.Calls() {
+		in, ok := call.Input.(*s3.GetObjectInput)
+		if !ok {
+			continue
+		}
+
+		if want := "bytes=0-6291455"; aws.ToString(in.Range) != want {
+			t.Errorf("Range = %v, want %v", aws.ToString(in.Range), want)
+		}
+		break
+	}
+}
+
+func TestFileUploadPartSize(t *testing.T) {
+	fsys, client := newTestFs(t, WithPartSize(7*1024*1024), WithUploadPartSize(6*1024*1024))
+	data := bytes.Repeat([]byte("a"), 13*1024*1024)
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, found := client.Object("test", "file")
+	if !found {
+		t.Fatal("object not found")
+	}
+
+	want := []int64{6 * 1024 * 1024, 6 * 1024 * 1024, 1024 * 1024}
+	if len(obj.PartSizes) != len(want) {
+		t.Fatalf("parts = %v, want %v", obj.PartSizes, want)
+	}
+
+	for i := range want {
+		if obj.PartSizes[i] != want[i] {
+			t.Errorf("part %d size = %d, want %d", i+1, obj.PartSizes[i], want[i])
+		}
+	}
+}
+
+type noSuchBucketClient struct {
+	*s3fstest.Client
+}
+
+func (c noSuchBucketClient) GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return nil, s3fstest.Error("GetObject", http.StatusNotFound, &types.NoSuchBucket{})
+}
+
+func TestFileReadBucketNotFound(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "file", []byte("data"))
+	fsys := New(noSuchBucketClient{client}, "test")
+
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = io.ReadAll(f)
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("Read() error = %v, want %v", err, ErrBucketNotFound)
+	}
+}
+
+type rangeNotSatisfiableClient struct {
+	*s3fstest.Client
+}
+
+func (c rangeNotSatisfiableClient) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if in.Range == nil {
+		return nil, errors.New("expected a ranged request")
+	}
+	return nil, s3fstest.Error("GetObject", http.StatusRequestedRangeNotSatisfiable, &smithy.GenericAPIError{Code: "InvalidRange"})
+}
+
+func TestFileReadRangeNotSatisfiable(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "empty", nil)
+	fsys := New(rangeNotSatisfiableClient{client}, "test")
+
+	f, err := fsys.Open("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	n, err := f.Read(make([]byte, 10))
+	if n != 0 || err != io.EOF {
+		t.Errorf("Read() = (%d, %v), want (0, EOF)", n, err)
+	}
+}
+
+func TestFileConditionalWrite(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), 11*1024*1024)
+
+	tests := []struct {
+		opts    func(etag string) []WriteOption
+		wantErr error
+		name    string
+		data    []byte
+		exists  bool
+	}{
+		{
+			name: "if none match on missing key",
+			opts: func(string) []WriteOption { return []WriteOption{WithIfNoneMatch()} },
+			data: []byte("content"),
+		},
+		{
+			name:    "if none match on existing key",
+			opts:    func(string) []WriteOption { return []WriteOption{WithIfNoneMatch()} },
+			data:    []byte("content"),
+			exists:  true,
+			wantErr: ErrPreconditionFailed,
+		},
+		{
+			name:    "if none match on existing key multipart",
+			opts:    func(string) []WriteOption { return []WriteOption{WithIfNoneMatch()} },
+			data:    large,
+			exists:  true,
+			wantErr: ErrPreconditionFailed,
+		},
+		{
+			name:   "if match with current etag",
+			opts:   func(etag string) []WriteOption { return []WriteOption{WithIfMatch(etag)} },
+			data:   []byte("content"),
+			exists: true,
+		},
+		{
+			name:    "if match with stale etag",
+			opts:    func(string) []WriteOption { return []WriteOption{WithIfMatch(`"stale"`)} },
+			data:    []byte("content"),
+			exists:  true,
+			wantErr: ErrPreconditionFailed,
+		},
+		{
+			name:    "if match with stale etag multipart",
+			opts:    func(string) []WriteOption { return []WriteOption{WithIfMatch(`"stale"`)} },
+			data:    large,
+			exists:  true,
+			wantErr: ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+
+			var etag string
+			if tt.exists {
+				client.Put("test", "file", []byte("original"))
+				obj, _ := client.Object("test", "file")
+				etag = obj.ETag
+			}
+
+			f, err := fsys.Create("file", tt.opts(etag)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Write(tt.data); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Close() error = %v, want %v", err, tt.wantErr)
+			}
+
+			obj, found := client.Object("test", "file")
+			if !found {
+				t.Fatal("object not found")
+			}
+
+			want := tt.data
+			if tt.wantErr != nil {
+				want = []byte("original")
+			}
+
+			if !bytes.Equal(obj.Data, want) {
+				t.Errorf("content = %q, want %q", obj.Data[:min(len(obj.Data), 16)], want[:min(len(want), 16)])
+			}
+		})
+	}
+}
+
+// overwritingClient overwrites the object before forwarding PutObject,
+// as a writer racing with the caller.
+type overwritingClient struct {
+	*s3fstest.Client
+}
+
+func (c overwritingClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.Put(aws.ToString(in.Bucket), aws.ToString(in.Key), []byte("concurrent"))
+	return c.Client.PutObject(ctx, in, optFns...)
+}
+
+func TestWriteIfUnmodifiedSince(t *testing.T) {
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		wantErr error
+		client  func(*s3fstest.Client) s3ApiClient
+		since   time.Time
+		name    string
+		want    string
+	}{
+		{
+			name:   "unmodified",
+			client: func(c *s3fstest.Client) s3ApiClient { return c },
+			since:  modTime.Add(time.Minute),
+			want:   "new",
+		},
+		{
+			name:   "modified at the same time",
+			client: func(c *s3fstest.Client) s3ApiClient { return c },
+			since:  modTime,
+			want:   "new",
+		},
+		{
+			name:    "modified after",
+			client:  func(c *s3fstest.Client) s3ApiClient { return c },
+			since:   modTime.Add(-time.Minute),
+			want:    "original",
+			wantErr: ErrPreconditionFailed,
+		},
+		{
+			name:    "modified while writing",
+			client:  func(c *s3fstest.Client) s3ApiClient { return overwritingClient{c} },
+			since:   modTime.Add(time.Minute),
+			want:    "concurrent",
+			wantErr: ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := s3fstest.New()
+			client.CreateBucket("test")
+			client.Now = func() time.Time { return modTime }
+			client.Put("test", "file", []byte("original"))
+			fsys := New(tt.client(client), "test")
+
+			err := fsys.WriteIfUnmodifiedSince("file", []byte("new"), tt.since)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WriteIfUnmodifiedSince() error = %v, want %v", err, tt.wantErr)
+			}
+
+			obj, _ := client.Object("test", "file")
+			if string(obj.Data) != tt.want {
+				t.Errorf("content = %q, want %q", obj.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteIfUnmodifiedSinceNotExist(t *testing.T) {
+	fsys, client := newTestFs(t)
+
+	if err := fsys.WriteIfUnmodifiedSince("file", []byte("new"), time.Now()); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("WriteIfUnmodifiedSince() error = %v, want %v", err, ErrPreconditionFailed)
+	}
+
+	if got := client.Keys("test"); len(got) != 0 {
+		t.Errorf("keys = %v, want none", got)
+	}
+}
+
+// blockingClient serves the first chunk of the object and then blocks until the context is done.
+type blockingClient struct {
+	*s3fstest.Client
+}
+
+func (c blockingClient) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	out, err := c.Client.GetObject(ctx, in, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Body = io.NopCloser(io.MultiReader(io.LimitReader(out.Body, 4), contextReader{ctx}))
+
+	return out, nil
+}
+
+type contextReader struct {
+	ctx context.Context
+}
+
+func (r contextReader) Read([]byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestFileReadContextCanceled(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "file", []byte("content"))
+	fsys := New(blockingClient{client}, "test")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f, err := fsys.OpenWithContext(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// the pipe only serves reads behind the written offset
+	b := make([]byte, 2)
+	if _, err := io.ReadFull(f, b); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(f)
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Read() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read() did not return after the context was canceled")
+	}
+
+	if _, err := f.(*File).Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Read(b); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Read() after Seek error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFileSeekEnd(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("content"))
+
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	file := f.(*File)
+
+	if off, err := file.Seek(-4, io.SeekEnd); err != nil || off != 3 {
+		t.Fatalf("Seek(-4, SeekEnd) = (%d, %v), want (3, nil)", off, err)
+	}
+
+	got, err := io.ReadAll(file)
+	if err != nil || string(got) != "tent" {
+		t.Fatalf("ReadAll() = (%q, %v), want (%q, nil)", got, err, "tent")
+	}
+
+	client.Reset()
+
+	if off, err := file.Seek(0, io.SeekEnd); err != nil || off != 7 {
+		t.Fatalf("Seek(0, SeekEnd) = (%d, %v), want (7, nil)", off, err)
+	}
+
+	n, err := file.Read(make([]byte, 10))
+	if n != 0 || err != io.EOF {
+		t.Errorf("Read() = (%d, %v), want (0, EOF)", n, err)
+	}
+
+	if got := client.Count("GetObject"); got != 0 {
+		t.Errorf("GetObject calls = %d, want 0", got)
+	}
+
+	if _, err := file.Seek(1, io.SeekEnd); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Seek(1, SeekEnd) error = %v, want %v", err, fs.ErrInvalid)
+	}
+}
+
+func TestOpenReader(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"), WithTimeout(time.Minute))
+	client.Put("test", "prefix/dir/file", []byte("content"))
+
+	r, err := fsys.OpenReader("dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "content" {
+		t.Errorf("content = %q, want %q", got, "content")
+	}
+
+	if got := client.Count("GetObject"); got != 1 {
+		t.Errorf("GetObject calls = %d, want 1", got)
+	}
+
+	if got := len(client.Calls()); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestOpenReaderNotExist(t *testing.T) {
+	fsys, _ := newTestFs(t)
+
+	if _, err := fsys.OpenReader("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenReader() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestFileClosed(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("content"))
+
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := f.(*File)
+
+	w, err := fsys.Create("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongMode := map[string]func() error{
+		"read":     func() error { _, err := w.Read(make([]byte, 1)); return err },
+		"read at":  func() error { _, err := w.ReadAt(make([]byte, 1), 0); return err },
+		"seek":     func() error { _, err := w.Seek(0, io.SeekStart); return err },
+		"write":    func() error { _, err := r.Write([]byte("a")); return err },
+		"write at": func() error { _, err := r.WriteAt([]byte("a"), 0); return err },
+	}
+
+	for name, op := range wrongMode {
+		if err := op(); !errors.Is(err, fs.ErrClosed) {
+			t.Errorf("%s: error = %v, want %v", name, err, fs.ErrClosed)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	afterClose := map[string]func() error{
+		"read":     func() error { _, err := r.Read(make([]byte, 1)); return err },
+		"read at":  func() error { _, err := r.ReadAt(make([]byte, 1), 0); return err },
+		"seek":     func() error { _, err := r.Seek(0, io.SeekStart); return err },
+		"write":    func() error { _, err := w.Write([]byte("a")); return err },
+		"write at": func() error { _, err := w.WriteAt([]byte("a"), 0); return err },
+		"truncate": func() error { return w.Truncate(0) },
+	}
+
+	for name, op := range afterClose {
+		if err := op(); !errors.Is(err, fs.ErrClosed) || !errors.Is(err, os.ErrClosed) {
+			t.Errorf("%s after close: error = %v, want %v", name, err, fs.ErrClosed)
+		}
+	}
+}
+
+type accessDeniedClient struct {
+	*s3fstest.Client
+}
+
+func (c accessDeniedClient) PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return nil, s3fstest.Error("PutObject", http.StatusForbidden, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"})
+}
+
+func (c accessDeniedClient) CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput, ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return nil, s3fstest.Error("CreateMultipartUpload", http.StatusForbidden, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"})
+}
+
+func TestFileCloseUploadError(t *testing.T) {
+	client := s3fstest.New()
+	client.CreateBucket("test")
+	fsys := New(accessDeniedClient{client}, "test")
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var apiErr smithy.APIError
+		if err := f.Close(); !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+			t.Errorf("Close() #%d error = %v, want AccessDenied", i+1, err)
+		}
+	}
+
+	if _, found := client.Object("test", "file"); found {
+		t.Error("object file was created")
+	}
+}
+
+func TestFileWriteUploadError(t *testing.T) {
+	client := s3fstest.New()
+	client.CreateBucket("test")
+	fsys := New(accessDeniedClient{client}, "test")
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// the upload starts once a part is buffered
+	chunk := make([]byte, 1024*1024)
+	deadline := time.Now().Add(5 * time.Second)
+
+	for err == nil && time.Now().Before(deadline) {
+		_, err = f.Write(chunk)
+		if f.written.Load() > 2*MinPartSize {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+		t.Errorf("Write() error = %v, want AccessDenied", err)
+	}
+}
+
+func TestFileCloseTwice(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("content"))
+
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := fsys.Create("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []fs.File{r, w, r, w} {
+		if err := f.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}
+
+	if got := client.Count("PutObject"); got != 1 {
+		t.Errorf("PutObject calls = %d, want 1", got)
+	}
+}
+
+// readCounter counts the Read calls, forwarding WriteTo to the File.
+type readCounter struct {
+	*File
+	reads int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	r.reads++
+	return r.File.Read(p)
+}
+
+func TestFileWriteTo(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 3<<20/16)
+
+	tests := []struct {
+		name   string
+		offset int64
+	}{
+		{name: "whole file", offset: 0},
+		{name: "after seek", offset: 1 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+			client.Put("test", "file", content)
+
+			f, err := fsys.Open("file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = f.Close() }()
+
+			file := f.(*File)
+			if _, err := file.Seek(tt.offset, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+
+			r := &readCounter{File: file}
+
+			var buf bytes.Buffer
+			n, err := io.Copy(&buf, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := content[tt.offset:]
+			if n != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("io.Copy() = %d bytes, want %d", n, len(want))
+			}
+
+			if r.reads != 0 {
+				t.Errorf("Read calls = %d, want 0", r.reads)
+			}
+
+			if file.offset != int64(len(content)) {
+				t.Errorf("offset = %d, want %d", file.offset, len(content))
+			}
+		})
+	}
+}
+
+func TestFileWriteToBucketNotFound(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "file", []byte("data"))
+	fsys := New(noSuchBucketClient{client}, "test")
+
+	f, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = f.(*File).WriteTo(io.Discard)
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("WriteTo() error = %v, want %v", err, ErrBucketNotFound)
+	}
+}
+
+func TestFileReadFrom(t *testing.T) {
+	fsys, client := newTestFs(t)
+	data := bytes.Repeat([]byte("0123456789abcdef"), 12<<20/16)
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hides bytes.Reader WriterTo, so io.Copy calls ReadFrom
+	n, err := io.Copy(f, struct{ io.Reader }{bytes.NewReader(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(len(data)) {
+		t.Errorf("io.Copy() = %d, want %d", n, len(data))
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, found := client.Object("test", "file")
+	if !found {
+		t.Fatal("object not found")
+	}
+
+	if got, want := sha256.Sum256(obj.Data), sha256.Sum256(data); got != want {
+		t.Errorf("checksum = %x, want %x", got, want)
+	}
+}
+
+func TestFileReadFromUploadError(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("existing"))
+
+	f, err := fsys.CreateWithContext(context.Background(), "file", WithIfNoneMatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.ReadFrom(strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Close() error = %v, want %v", err, ErrPreconditionFailed)
+	}
+}
+
+// corruptingClient flips the first byte of the PutObject body.
+type corruptingClient struct {
+	*s3fstest.Client
+}
+
+func (c corruptingClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	data[0] ^= 0xff
+	in.Body = bytes.NewReader(data)
+
+	return c.Client.PutObject(ctx, in, optFns...)
+}
+
+func TestFileContentMD5(t *testing.T) {
+	tests := []struct {
+		wantErr error
+		client  func(*s3fstest.Client) s3ApiClient
+		name    string
+	}{
+		{
+			name:   "valid",
+			client: func(c *s3fstest.Client) s3ApiClient { return c },
+		},
+		{
+			name:    "corrupted",
+			client:  func(c *s3fstest.Client) s3ApiClient { return corruptingClient{c} },
+			wantErr: ErrBadDigest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := s3fstest.New()
+			client.CreateBucket("test")
+			fsys := New(tt.client(client), "test")
+
+			f, err := fsys.Create("file", WithContentMD5())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Write([]byte("content")); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Close() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var in *s3.PutObjectInput
+			for _, call := range client.Calls() {
+				if call.Op == "PutObject" {
+					in = call.Input.(*s3.PutObjectInput)
+				}
+			}
+			if got := aws.ToString(in.ContentMD5); got != "mgNkuembtIDdJeHwKEyFVQ==" {
+				t.Errorf("ContentMD5 = %q, want %q", got, "mgNkuembtIDdJeHwKEyFVQ==")
+			}
+		})
+	}
+}
+
+func TestFileStatAfterClose(t *testing.T) {
+	fsys, client := newTestFs(t)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	client.Now = func() time.Time { return modTime }
+	data := bytes.Repeat([]byte("a"), 1000)
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// overlaps the bytes already written, the size is unchanged
+	if _, err := f.WriteAt(data[:100], 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() != int64(len(data)) {
+		t.Errorf("Size() = %d, want %d", info.Size(), len(data))
+	}
+
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("ModTime() = %v, want %v", info.ModTime(), modTime)
+	}
+}
+
+func TestCreateSized(t *testing.T) {
+	fsys, client := newTestFs(t)
+	data := bytes.Repeat([]byte("a"), 1024)
+
+	f, err := fsys.CreateSized(context.Background(), "file", int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Count("PutObject"); got != 1 {
+		t.Errorf("PutObject calls = %d, want 1", got)
+	}
+
+	if got := client.Count("CreateMultipartUpload"); got != 0 {
+		t.Errorf("CreateMultipartUpload calls = %d, want 0", got)
+	}
+
+	var in *s3.PutObjectInput
+	for _, call := range client.Calls() {
+		if call.Op == "PutObject" {
+			in = call.Input.(*s3.PutObjectInput)
+		}
+	}
+	if got := aws.ToInt64(in.ContentLength); got != int64(len(data)) {
+		t.Errorf("ContentLength = %d, want %d", got, len(data))
+	}
+
+	obj, _ := client.Object("test", "file")
+	if !bytes.Equal(obj.Data, data) {
+		t.Error("content mismatch")
+	}
+}
+
+func TestCreateSizedShortWrite(t *testing.T) {
+	fsys, client := newTestFs(t)
+
+	f, err := fsys.CreateSized(context.Background(), "file", 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("short")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Close() error = %v, want %v", err, fs.ErrInvalid)
+	}
+
+	if _, found := client.Object("test", "file"); found {
+		t.Error("object file was created")
+	}
+}
+
+func TestFileDeterministicParts(t *testing.T) {
+	fsys, client := newTestFs(t, WithUploadPartSize(5*1024*1024))
+	data := bytes.Repeat([]byte("0123456789abcdef"), 12<<20/16)
+
+	etags := make([]string, 0, 2)
+	for _, name := range []string{"a", "b"} {
+		f, err := fsys.Create(name, WithDeterministicParts())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// uneven writes do not change the parts
+		for rest := data; len(rest) > 0; {
+			n := min(len(rest), 3*1024*1024+len(name))
+			if _, err := f.Write(rest[:n]); err != nil {
+				t.Fatal(err)
+			}
+			rest = rest[n:]
+		}
+
+		if got := client.Count("CreateMultipartUpload"); got != len(etags) {
+			t.Fatalf("CreateMultipartUpload calls before Close = %d, want %d", got, len(etags))
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		obj, found := client.Object("test", name)
+		if !found {
+			t.Fatal("object not found")
+		}
+
+		want := []int64{5 * 1024 * 1024, 5 * 1024 * 1024, 2 * 1024 * 1024}
+		if !slices.Equal(obj.PartSizes, want) {
+			t.Errorf("parts = %v, want %v", obj.PartSizes, want)
+		}
+
+		etags = append(etags, obj.ETag)
+	}
+
+	if etags[0] != etags[1] {
+		t.Errorf("ETag = %s, want %s", etags[1], etags[0])
+	}
+
+	if !strings.HasSuffix(etags[0], `-3"`) {
+		t.Errorf("ETag = %s, want a multipart ETag of 3 parts", etags[0])
+	}
+}
@@ -3,20 +3,24 @@ package s3fs
 import (
 	"io/fs"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type FileInfo struct {
 	modTime time.Time
-	name    string
-	size    int64
-	mode    fs.FileMode
+	// sys is the SDK value the info was built from, returned by Sys
+	sys  any
+	name string
+	size int64
+	mode fs.FileMode
 }
 
-func directoryFileInfo(name string) FileInfo {
+func directoryFileInfo(name string, modTime time.Time) FileInfo {
 	return FileInfo{
 		name:    name,
 		mode:    0o755 | fs.ModeDir,
-		modTime: time.Now(),
+		modTime: modTime,
 	}
 }
 
@@ -29,11 +33,23 @@ func regularFileInfo(name string, size int64, modTime time.Time) FileInfo {
 	}
 }
 
+// objectFileInfo returns the FileInfo of a listed object, carrying obj as Sys.
+// now is the modification time of objects listed without one.
+func objectFileInfo(name string, obj types.Object, now func() time.Time) FileInfo {
+	info := regularFileInfo(name, getOrElse(obj.Size, zeroInt64), getOrElse(obj.LastModified, now))
+	info.sys = obj
+	return info
+}
+
 func (i *FileInfo) Name() string               { return i.name }
 func (i *FileInfo) Size() int64                { return i.size }
 func (i *FileInfo) Type() fs.FileMode          { return i.mode }
 func (i *FileInfo) ModTime() time.Time         { return i.modTime }
 func (i *FileInfo) IsDir() bool                { return i.mode&fs.ModeDir != 0 }
-func (i *FileInfo) Sys() interface{}           { return nil }
 func (i *FileInfo) Info() (fs.FileInfo, error) { return i, nil }
 func (i *FileInfo) Mode() fs.FileMode          { return i.mode }
+
+// Sys returns the types.Object of the ListObjectsV2 call for the files found by listing,
+// such as the entries of ReadDir, or nil.
+// The fields of the object depend on the version of the SDK.
+func (i *FileInfo) Sys() interface{} { return i.sys }
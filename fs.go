@@ -5,14 +5,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
 	"path"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 const (
@@ -22,8 +28,18 @@ const (
 	currentDirName = "."
 	// directoryFile is a file created to ensure a directory (prefix) exists.
 	directoryFile = ".keep"
-	// minPartSize is the minimum size allowed in multipart download/uploads.
-	minPartSize = 5 * 1024 * 1024
+	// maxPageKeys is the maximum number of keys of a listing page.
+	maxPageKeys = 1000
+)
+
+// S3 multipart upload limits.
+const (
+	// MinPartSize is the minimum size of a part in multipart downloads and uploads.
+	MinPartSize = 5 * 1024 * 1024
+	// MaxPartSize is the maximum size of a part in multipart uploads.
+	MaxPartSize = 5 * 1024 * 1024 * 1024
+	// MaxParts is the maximum number of parts of a multipart upload.
+	MaxParts = 10000
 )
 
 var (
@@ -33,13 +49,45 @@ var (
 
 // Fs is fs.FS S3 filesystem abstraction.
 type Fs struct {
-	client        s3ApiClient
-	bucket        string
-	prefix        string
-	tempDir       string
-	directoryFile string
-	timeout       time.Duration
-	partSize      int64
+	client s3ApiClient
+	// renameClient deletes the source of Rename, retrying as set by WithRenameRetry
+	renameClient     s3ApiClient
+	recorder         Recorder
+	logger           LogFunc
+	retryClassifier  func(error) bool
+	fallbackNotice   func(op, reason string)
+	contentTypes     map[string]string
+	now              func() time.Time
+	cloudFront       *cloudFront
+	httpClient       *http.Client
+	statCache        *statCache
+	listCache        *listCache
+	rateLimiter      *rateLimiter
+	regionCheck      *regionCheckClient
+	bucket           string
+	prefix           string
+	tempDir          string
+	directoryFile    string
+	acl              types.ObjectCannedACL
+	checksumMode     types.ChecksumMode
+	uploadChecksum   types.ChecksumAlgorithm
+	s3Options        []func(*s3.Options)
+	timeout          time.Duration
+	retryBase        time.Duration
+	renameRetryBase  time.Duration
+	retryAttempts    int
+	renameAttempts   int
+	partSize         int64
+	downloadPartSize int64
+	uploadPartSize   int64
+	statStrategy     StatStrategy
+	maxKeys          int32
+	maxListPages     int
+	rawKeys          bool
+	versionBrowsing  bool
+	sanitizeKeys     bool
+	partialListing   bool
+	autoDecompress   bool
 }
 
 // Option is a Fs configuration.
@@ -54,6 +102,17 @@ func WithPrefix(prefix string) Option {
 	}
 }
 
+// WithRawKeys disables the normalization of names, which are used verbatim as keys,
+// joined to the prefix if any.
+// Names such as "a//b" or "/a" are kept as is instead of becoming "a/b" and "a",
+// but "." and ".." elements are not resolved: only "." names the root directory.
+// Nothing rejects or resolves ".." either, so callers are responsible for the names they use.
+func WithRawKeys() Option {
+	return func(f *Fs) {
+		f.rawKeys = true
+	}
+}
+
 // WithTimeout sets the timeout when interacting with S3.
 func WithTimeout(d time.Duration) Option {
 	return func(f *Fs) {
@@ -62,14 +121,37 @@ func WithTimeout(d time.Duration) Option {
 }
 
 // WithPartSize sets the part size used on multipart download or upload.
+// Sizes smaller than MinPartSize are ignored.
 func WithPartSize(size int64) Option {
 	return func(f *Fs) {
-		if size > minPartSize {
+		if size >= MinPartSize {
 			f.partSize = size
 		}
 	}
 }
 
+// WithDownloadPartSize sets the part size used on multipart download,
+// overriding the one set by WithPartSize.
+// Sizes smaller than MinPartSize are ignored.
+func WithDownloadPartSize(size int64) Option {
+	return func(f *Fs) {
+		if size >= MinPartSize {
+			f.downloadPartSize = size
+		}
+	}
+}
+
+// WithUploadPartSize sets the part size used on multipart upload,
+// overriding the one set by WithPartSize.
+// Sizes smaller than MinPartSize are ignored.
+func WithUploadPartSize(size int64) Option {
+	return func(f *Fs) {
+		if size >= MinPartSize {
+			f.uploadPartSize = size
+		}
+	}
+}
+
 // WithTemporaryDirectory sets the temporary directory
 // where the unlinked temporary files will be created.
 func WithTemporaryDirectory(dirName string) Option {
@@ -87,22 +169,147 @@ func WithDirectoryFile(s string) Option {
 	}
 }
 
+// WithACL sets the canned ACL applied to created objects.
+func WithACL(acl types.ObjectCannedACL) Option {
+	return func(f *Fs) {
+		f.acl = acl
+	}
+}
+
+// WithStatCache caches Stat results in memory for ttl,
+// entries are invalidated when the named file or directory is changed through the Fs.
+func WithStatCache(ttl time.Duration) Option {
+	return func(f *Fs) {
+		if ttl > 0 {
+			f.statCache = newStatCache(ttl, statCacheSize)
+		}
+	}
+}
+
+// WithListCache caches ReadDir results in memory for ttl, such as for directory browsers,
+// listings are invalidated when a file or directory under them is changed through the Fs.
+func WithListCache(ttl time.Duration) Option {
+	return func(f *Fs) {
+		if ttl > 0 {
+			f.listCache = newListCache(ttl, listCacheSize)
+		}
+	}
+}
+
+// WithPartialListing makes ReadDir return the entries listed so far when a page fails,
+// such as for best-effort browsers, with an error matching ErrIncompleteListing
+// and the error of the page. Partial listings are not cached.
+func WithPartialListing() Option {
+	return func(f *Fs) {
+		f.partialListing = true
+	}
+}
+
+// WithClock sets the clock used for the modification time of directories,
+// and of written files whose object cannot be read back, and for the expiry of the caches, instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(f *Fs) {
+		if now != nil {
+			f.now = now
+		}
+	}
+}
+
+// WithMaxKeys sets the number of keys of each listing page,
+// such as smaller pages to render the first entries of a directory sooner,
+// the S3 default of 1000 otherwise.
+// Values outside 1 to 1000 are ignored.
+func WithMaxKeys(n int32) Option {
+	return func(f *Fs) {
+		if n >= 1 && n <= maxPageKeys {
+			f.maxKeys = n
+		}
+	}
+}
+
+// WithMaxListPages stops the listings of ReadDir, ReadFiles, ReadDirFlat, RangeDir, WalkDir and ListBySuffix
+// after n pages, as a safety valve against listing an enormous prefix by accident.
+// The entries of the first n pages are returned with an error matching ErrListingTruncated.
+func WithMaxListPages(n int) Option {
+	return func(f *Fs) {
+		f.maxListPages = n
+	}
+}
+
 // New creates a S3 fs abstraction
 func New(client s3ApiClient, bucket string, opts ...Option) *Fs {
 	f := &Fs{
 		client:        client,
 		bucket:        bucket,
-		partSize:      minPartSize,
+		partSize:      MinPartSize,
 		directoryFile: directoryFile,
+		now:           time.Now,
 	}
 
 	for _, o := range opts {
 		o(f)
 	}
 
+	// the caches may be configured before the clock and the raw keys
+	if f.statCache != nil {
+		f.statCache.now = f.now
+		f.statCache.dir = f.dir
+	}
+
+	if f.listCache != nil {
+		f.listCache.now = f.now
+		f.listCache.dir = f.dir
+	}
+
+	if len(f.s3Options) > 0 {
+		f.client = &optionsClient{client: f.client, optFns: f.s3Options}
+	}
+
+	if f.regionCheck != nil {
+		inner := f.client
+		f.regionCheck.client = inner
+		f.regionCheck.checkFn = func(ctx context.Context) error { return f.checkRegion(ctx, inner) }
+		f.client = f.regionCheck
+	}
+
+	if f.logger != nil {
+		f.client = &logClient{client: f.client, log: f.logger}
+	}
+
+	unretried := f.client
+
+	if f.retryAttempts > 1 {
+		f.client = &retryClient{client: unretried, retryable: f.retryClassifier, maxAttempts: f.retryAttempts, base: f.retryBase}
+	}
+
+	// the rename retries replace the ones of WithRetry, instead of multiplying them
+	f.renameClient = f.client
+	if f.renameAttempts > 1 {
+		f.renameClient = &retryClient{client: unretried, retryable: f.retryClassifier, maxAttempts: f.renameAttempts, base: f.renameRetryBase}
+	}
+
+	if f.recorder != nil {
+		f.client = &metricsClient{client: f.client, recorder: f.recorder}
+		f.renameClient = &metricsClient{client: f.renameClient, recorder: f.recorder}
+	}
+
+	if f.downloadPartSize == 0 {
+		f.downloadPartSize = f.partSize
+	}
+
+	if f.uploadPartSize == 0 {
+		f.uploadPartSize = f.partSize
+	}
+
 	return f
 }
 
+// EffectivePartSize returns the part size used on multipart upload,
+// after ignoring the sizes smaller than MinPartSize.
+func (f *Fs) EffectivePartSize() int64 {
+	return f.uploadPartSize
+}
+
 // Open opens the named file or directory for reading.
 func (f *Fs) Open(name string) (fs.File, error) {
 	return f.OpenWithContext(context.Background(), name)
@@ -110,6 +317,10 @@ func (f *Fs) Open(name string) (fs.File, error) {
 
 // OpenWithContext opens the named file or directory for reading.
 func (f *Fs) OpenWithContext(ctx context.Context, name string) (fs.File, error) {
+	if vp, ok := f.parseVersionPath(name); ok {
+		return f.versionOpen(ctx, name, vp)
+	}
+
 	info, err := f.StatWithContext(ctx, name)
 	if err != nil {
 		return nil, err
@@ -117,301 +328,968 @@ func (f *Fs) OpenWithContext(ctx context.Context, name string) (fs.File, error)
 
 	if info.IsDir() {
 		return &Directory{
+			ctx:      ctx,
 			fs:       f,
+			name:     f.cleanPath(name),
 			fileInfo: info,
+			marker:   f.withPrefix(name, f.directoryFile),
 		}, nil
 	}
 
+	// a trailing slash names a directory
+	if strings.HasSuffix(name, pathSeparator) && !f.rawKeys {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrNotDirectory}
+	}
+
 	file := &File{
+		ctx:  ctx,
 		fs:   f,
 		info: info,
 	}
-	return file, file.openReaderAt(ctx, 0)
-}
 
-// Stat returns a FileInfo describing the named file.
-func (f *Fs) Stat(name string) (FileInfo, error) {
-	return f.StatWithContext(context.Background(), name)
-}
+	if err := file.openReaderAt(ctx, 0); err != nil {
+		return file, err
+	}
 
-// StatWithContext returns a FileInfo describing the named file.
-func (f *Fs) StatWithContext(ctx context.Context, name string) (FileInfo, error) {
-	// "." and "/" are always directories
-	if cleanPath(name) == "" {
-		return directoryFileInfo(currentDirName), nil
+	if f.autoDecompress {
+		return f.openDecompressed(ctx, name, file)
 	}
 
-	opts := &s3.ListObjectsV2Input{
-		Bucket:    aws.String(f.bucket),
-		Prefix:    aws.String(f.withPrefix(name)),
-		Delimiter: aws.String(pathSeparator),
-		MaxKeys:   aws.Int32(1),
+	return file, nil
+}
+
+// OpenReader opens the named file for reading,
+// returning the object body as a stream, from CloudFront when WithCloudFront is set.
+// Unlike Open there is no directory detection nor support for ReadAt or Seek.
+func (f *Fs) OpenReader(name string) (io.ReadCloser, error) {
+	return f.OpenReaderWithContext(context.Background(), name)
+}
+
+// OpenReaderWithContext opens the named file for reading,
+// returning the object body as a stream, from CloudFront when WithCloudFront is set.
+// Unlike Open there is no directory detection nor support for ReadAt or Seek.
+func (f *Fs) OpenReaderWithContext(ctx context.Context, name string) (io.ReadCloser, error) {
+	if f.cloudFront != nil {
+		return f.openCloudFront(ctx, name)
 	}
 
+	cancelFn := context.CancelFunc(func() {})
 	if f.timeout > 0 {
-		var cancelFn context.CancelFunc
 		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
-		defer cancelFn()
 	}
 
-	res, err := f.client.ListObjectsV2(ctx, opts)
+	res, err := f.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name)),
+	})
 	if err != nil {
-		return FileInfo{}, err
+		cancelFn()
+		if isNotFound(err) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return nil, mapError(err)
 	}
 
-	prefixedName := f.withPrefix(name)
+	body := &bodyReader{ReadCloser: res.Body, ctx: ctx, fs: f, cancelFn: cancelFn}
 
-	for _, el := range res.CommonPrefixes {
-		if *el.Prefix == prefixedName+pathSeparator {
-			return directoryFileInfo(cleanPath(name)), nil
-		}
+	if f.autoDecompress && isGzip(aws.ToString(res.ContentEncoding)) {
+		return &gzipReadCloser{body: body}, nil
 	}
 
-	for _, el := range res.Contents {
-		if *el.Key == prefixedName {
-			return regularFileInfo(cleanPath(name), getOrElse(el.Size, zeroInt64), getOrElse(el.LastModified, time.Now)), nil
+	return body, nil
+}
+
+// bodyReader releases the request context once the body is closed.
+type bodyReader struct {
+	io.ReadCloser
+	ctx      context.Context
+	fs       *Fs
+	cancelFn context.CancelFunc
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.fs.addBytes(BytesRead, n)
+	if n > 0 {
+		if waitErr := r.fs.waitRate(r.ctx, n); waitErr != nil {
+			return n, waitErr
 		}
 	}
+	return n, err
+}
 
-	return FileInfo{}, fs.ErrNotExist
+func (r *bodyReader) Close() error {
+	defer r.cancelFn()
+	return r.ReadCloser.Close()
 }
 
-// Create opens a named file for writing.
-func (f *Fs) Create(name string) (*File, error) {
-	return f.CreateWithContext(context.Background(), name)
+// Stat returns a FileInfo describing the named file.
+// The modification time of a directory is the time of the call,
+// the one of its directory file is reported by the Stat of the opened Directory once it is read.
+func (f *Fs) Stat(name string) (FileInfo, error) {
+	return f.StatWithContext(context.Background(), name)
 }
 
-// CreateWithContext opens a named file for writing.
-func (f *Fs) CreateWithContext(ctx context.Context, name string) (*File, error) {
-	info, err := f.StatWithContext(ctx, name)
-	if err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return nil, err
+// StatWithContext returns a FileInfo describing the named file, as Stat.
+func (f *Fs) StatWithContext(ctx context.Context, name string) (FileInfo, error) {
+	if vp, ok := f.parseVersionPath(name); ok {
+		return f.versionStat(ctx, name, vp)
 	}
 
-	if info.IsDir() {
-		return nil, fmt.Errorf("named file is a directory: %w", fs.ErrExist)
+	if info, found := f.statCache.get(f.cleanPath(name)); found {
+		return info, nil
 	}
 
-	file := &File{
-		fs:   f,
-		info: regularFileInfo(cleanPath(name), 0, time.Now()),
+	info, err := f.stat(ctx, name)
+	if err != nil {
+		return FileInfo{}, err
 	}
 
-	return file, file.openWriter(ctx)
-}
+	f.statCache.set(f.cleanPath(name), info)
 
-// CreateDir creates a name directory
-// Since S3 doesn't have the concept of directories, an empty file .keep is created.
-func (f *Fs) CreateDir(name string) (fs.DirEntry, error) {
-	return f.CreateDirWithContext(context.Background(), name)
+	return info, nil
 }
 
-// CreateDirWithContext creates a name directory
-// Since S3 doesn't have the concept of directories, an empty file .keep is created.
-func (f *Fs) CreateDirWithContext(ctx context.Context, name string) (fs.DirEntry, error) {
-	info, err := f.StatWithContext(ctx, name)
-	if err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return nil, err
+func (f *Fs) stat(ctx context.Context, name string) (FileInfo, error) {
+	// "." and "/" are always directories, once the bucket is known to be reachable
+	if f.cleanPath(name) == "" {
+		return f.rootStat(ctx)
 	}
 
-	if err == nil && !info.IsDir() {
-		return nil, fmt.Errorf("a file with the same name already exists: %w", fs.ErrExist)
+	switch f.statStrategy {
+	case HeadBased:
+		return f.objectStat(ctx, name, f.headObject)
+	case AttributesBased:
+		return f.objectStat(ctx, name, f.objectAttributes)
+	default:
+		return f.listStat(ctx, name)
 	}
+}
 
-	if info.IsDir() {
-		return nil, fmt.Errorf("a directory with the same name already exists: %w", fs.ErrExist)
+// rootStat checks the bucket can be listed, with a listing without keys.
+// With a prefix, the listing has its first key, which is the prefix itself when it names an object.
+func (f *Fs) rootStat(ctx context.Context) (FileInfo, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
 	}
 
-	if f.timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, f.timeout)
-		defer cancel()
+	maxKeys := int32(0)
+	if f.prefix != "" {
+		maxKeys = 1
 	}
 
-	_, err = f.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(f.bucket),
-		Key:    aws.String(f.withPrefix(name, f.directoryFile)),
-		Body:   bytes.NewReader(nil),
+	res, err := f.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucket),
+		Prefix:  nonEmpty(f.prefix),
+		MaxKeys: aws.Int32(maxKeys),
 	})
 	if err != nil {
-		return nil, err
+		return FileInfo{}, mapError(err)
 	}
 
-	dir := &Directory{
-		fs:       f,
-		fileInfo: directoryFileInfo(cleanPath(name)),
+	if f.prefix != "" && len(res.Contents) > 0 && aws.ToString(res.Contents[0].Key) == f.prefix {
+		return FileInfo{}, &fs.PathError{Op: "stat", Path: f.prefix, Err: ErrInvalidPrefix}
 	}
 
-	return dir, nil
-}
-
-// ReadDir reads the named directory
-// and returns a list of directory entries sorted by filename.
-func (f *Fs) ReadDir(dirName string) ([]fs.DirEntry, error) {
-	return f.ReadDirWithContext(context.Background(), dirName)
+	return directoryFileInfo(currentDirName, f.now()), nil
 }
 
-// ReadDirWithContext reads the named directory
-// and returns a list of directory entries sorted by filename.
-func (f *Fs) ReadDirWithContext(ctx context.Context, dirName string) ([]fs.DirEntry, error) {
-	dirName = cleanPath(dirName)
-
-	info, err := f.StatWithContext(ctx, dirName)
-	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return []fs.DirEntry{}, nil
-		}
-		return nil, err
-	}
-
-	if !info.IsDir() {
-		return nil, fmt.Errorf("cannot list a file: %w", fs.ErrInvalid)
-	}
+// listStat finds the named file or directory with a single listing.
+func (f *Fs) listStat(ctx context.Context, name string) (FileInfo, error) {
+	prefixedName := f.withPrefix(name)
+	dirPrefix := prefixedName + pathSeparator
 
 	opts := &s3.ListObjectsV2Input{
 		Bucket:    aws.String(f.bucket),
-		Prefix:    aws.String(f.withPrefix(dirName) + pathSeparator),
+		Prefix:    aws.String(prefixedName),
 		Delimiter: aws.String(pathSeparator),
+		MaxKeys:   f.pageSize(),
 	}
 
-	if dirName == "" {
-		opts.Prefix = nil
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
 	}
 
-	seenPrefixes := map[string]struct{}{
-		currentDirName: {},
-		pathSeparator:  {},
-	}
+	// Other keys may share the name as prefix (e.g. "foo-extra.txt" for "foo"),
+	// the listing is scanned until it goes past the directory prefix.
+	var file *types.Object
 
 	paginator := s3.NewListObjectsV2Paginator(f.client, opts)
 
-	result := []fs.DirEntry{
-		&Directory{
-			fs:       f,
-			fileInfo: directoryFileInfo(currentDirName),
-		},
-	}
-
 	for paginator.HasMorePages() {
-		var cancelFn context.CancelFunc
-		if f.timeout > 0 {
-			ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
-		}
-
-		page, err := paginator.NextPage(ctx)
-
-		if cancelFn != nil {
-			cancelFn()
-		}
+		res, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, err
+			return FileInfo{}, mapError(err)
 		}
 
-		for _, p := range page.CommonPrefixes {
-			if p.Prefix == nil {
-				continue
-			}
-
-			dir, _ := baseName(*p.Prefix)
+		done := false
 
-			if _, found := seenPrefixes[dir]; found {
-				continue
+		for _, el := range res.CommonPrefixes {
+			p := aws.ToString(el.Prefix)
+			if p == dirPrefix {
+				return directoryFileInfo(f.cleanPath(name), f.now()), nil
 			}
-
-			seenPrefixes[dir] = struct{}{}
-
-			result = append(result, &Directory{
-				fs:       f,
-				fileInfo: directoryFileInfo(dir),
-			})
+			done = done || p > dirPrefix
 		}
 
-		for _, obj := range page.Contents {
-			if obj.Key == nil {
-				continue
-			}
-
-			name, mode := baseName(*obj.Key)
-			if name == "" || name == pathSeparator || name == f.directoryFile {
-				continue
-			}
-
-			if mode&fs.ModeDir != 0 {
-				if _, found := seenPrefixes[name]; found {
-					continue
-				}
-				seenPrefixes[name] = struct{}{}
+		for i, el := range res.Contents {
+			key := aws.ToString(el.Key)
+			if key == prefixedName {
+				file = &res.Contents[i]
 			}
+			done = done || key > dirPrefix
+		}
 
-			result = append(result, &File{
-				fs:   f,
-				info: regularFileInfo(name, getOrElse(obj.Size, zeroInt64), getOrElse(obj.LastModified, time.Now)),
-			})
+		if done {
+			break
 		}
 	}
 
-	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	if file != nil {
+		return objectFileInfo(f.cleanPath(name), *file, f.now), nil
+	}
 
-	return result, nil
+	return FileInfo{}, fs.ErrNotExist
 }
 
-// Remove removes the named file.
-func (f *Fs) Remove(filename string) error {
-	return f.RemoveWithContext(context.Background(), filename)
+// Create opens a named file for writing.
+func (f *Fs) Create(name string, opts ...WriteOption) (*File, error) {
+	return f.CreateWithContext(context.Background(), name, opts...)
 }
 
-// RemoveWithContext removes the named file.
-func (f *Fs) RemoveWithContext(ctx context.Context, fileName string) error {
-	info, err := f.StatWithContext(ctx, fileName)
-	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return nil
-		}
-		return err
+// CreateWithContext opens a named file for writing.
+func (f *Fs) CreateWithContext(ctx context.Context, name string, opts ...WriteOption) (*File, error) {
+	if err := f.checkKey("create", name); err != nil {
+		return nil, err
 	}
 
-	if info.IsDir() {
-		return fmt.Errorf("named file is a directory: %w", fs.ErrInvalid)
+	// the root is a directory, even without keys
+	if f.cleanPath(name) == "" {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fmt.Errorf("%w: %w", ErrIsDirectory, fs.ErrExist)}
 	}
 
-	if f.timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, f.timeout)
-		defer cancel()
+	isDir, err := f.hasKeysUnder(ctx, name)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = f.client.DeleteObject(ctx, &s3.DeleteObjectInput{
-		Bucket: aws.String(f.bucket),
-		Key:    aws.String(f.withPrefix(fileName)),
-	})
-	return err
-}
+	if isDir {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fmt.Errorf("%w: %w", ErrIsDirectory, fs.ErrExist)}
+	}
 
-// Rename renames (moves) oldpath to newpath.
-// If newpath already exists and is not a directory, Rename replaces it.
-func (f *Fs) Rename(oldpath, newpath string) error {
-	return f.RenameWithContext(context.Background(), oldpath, newpath)
-}
+	f.invalidate(f.cleanPath(name))
 
-// RenameWithContext renames (moves) oldpath to newpath.
-// If newpath already exists and is not a directory, Rename replaces it.
-func (f *Fs) RenameWithContext(ctx context.Context, oldpath, newpath string) error {
-	oldInfo, err := f.StatWithContext(ctx, oldpath)
-	if err != nil {
-		return err
+	file := &File{
+		ctx:  ctx,
+		fs:   f,
+		info: regularFileInfo(f.cleanPath(name), 0, f.now()),
 	}
 
-	if oldInfo.IsDir() {
-		return fmt.Errorf("oldpath is a directory: %w", fs.ErrInvalid)
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	newInfo, err := f.StatWithContext(ctx, newpath)
-	if err != nil && !errors.Is(err, fs.ErrNotExist) {
-		return err
+	if o.deterministicParts {
+		return file, file.openBuffer(ctx, os.O_WRONLY, false, opts)
 	}
 
-	if newInfo.IsDir() {
-		return fmt.Errorf("newpath is a directory: %w", fs.ErrInvalid)
+	return file, file.openWriter(ctx, opts...)
+}
+
+// WriteIfUnmodifiedSince writes data to the named file only if it was not modified after t,
+// such as in read-modify-write loops keyed on the modification time,
+// otherwise it returns ErrPreconditionFailed, as for a file that does not exist.
+// S3 has no If-Unmodified-Since on writes: the modification time is checked first,
+// and the write is conditioned on the ETag seen, so a change in between also fails.
+func (f *Fs) WriteIfUnmodifiedSince(name string, data []byte, t time.Time) error {
+	return f.WriteIfUnmodifiedSinceWithContext(context.Background(), name, data, t)
+}
+
+// WriteIfUnmodifiedSinceWithContext writes data to the named file only if it was not modified after t,
+// such as in read-modify-write loops keyed on the modification time,
+// otherwise it returns ErrPreconditionFailed, as for a file that does not exist.
+// S3 has no If-Unmodified-Since on writes: the modification time is checked first,
+// and the write is conditioned on the ETag seen, so a change in between also fails.
+func (f *Fs) WriteIfUnmodifiedSinceWithContext(ctx context.Context, name string, data []byte, t time.Time) error {
+	if err := f.checkKey("write", name); err != nil {
+		return err
+	}
+
+	headCtx := ctx
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		headCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(headCtx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return &fs.PathError{Op: "write", Path: name, Err: ErrPreconditionFailed}
+		}
+		return mapError(err)
+	}
+
+	if aws.ToTime(res.LastModified).After(t) {
+		return &fs.PathError{Op: "write", Path: name, Err: ErrPreconditionFailed}
+	}
+
+	file, err := f.CreateWithContext(ctx, name, WithIfMatch(aws.ToString(res.ETag)))
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// CreateSized opens a named file for writing size bytes.
+// A file smaller than the upload part size is buffered in the temporary directory
+// and uploaded on Close with a single PutObject of a known length,
+// instead of streaming it to the uploader. Close then fails if size bytes were not written.
+func (f *Fs) CreateSized(ctx context.Context, name string, size int64) (*File, error) {
+	if size < 0 {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if size >= f.uploadPartSize {
+		return f.CreateWithContext(ctx, name)
+	}
+
+	// the uploader sends a seekable body smaller than a part with a single PutObject
+	return f.CreateWithContext(ctx, name, WithDeterministicParts(), func(o *writeOptions) {
+		o.size = &size
+	})
+}
+
+// hasKeysUnder reports whether any key is under the named directory,
+// listing a single key instead of relying on Stat, whose strategy or cache may not see it.
+func (f *Fs) hasKeysUnder(ctx context.Context, name string) (bool, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucket),
+		Prefix:  aws.String(f.withPrefix(name) + pathSeparator),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return false, mapError(err)
+	}
+
+	return len(res.Contents) > 0, nil
+}
+
+// CreateDir creates a name directory
+// Since S3 doesn't have the concept of directories, an empty file .keep is created.
+func (f *Fs) CreateDir(name string) (*Directory, error) {
+	return f.CreateDirWithContext(context.Background(), name)
+}
+
+// CreateDirWithContext creates a name directory
+// Since S3 doesn't have the concept of directories, an empty file .keep is created.
+func (f *Fs) CreateDirWithContext(ctx context.Context, name string) (*Directory, error) {
+	if err := f.checkKey("mkdir", name, f.directoryFile); err != nil {
+		return nil, err
+	}
+
+	info, err := f.StatWithContext(ctx, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("a file with the same name already exists: %w", fs.ErrExist)
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("a directory with the same name already exists: %w", fs.ErrExist)
+	}
+
+	if err := f.putDirectoryFile(ctx, name); err != nil {
+		return nil, err
+	}
+
+	dir := &Directory{
+		fs:        f,
+		name:      f.cleanPath(name),
+		fileInfo:  directoryFileInfo(f.cleanPath(name), f.now()),
+		marker:    f.withPrefix(name, f.directoryFile),
+		hasMarker: true,
+	}
+
+	return dir, nil
+}
+
+// MkdirAll creates the named directory along with any missing parents,
+// writing a directory file at each level that does not exist yet.
+// It returns nil if the directory already exists,
+// and fs.ErrExist if a file collides with a path element.
+func (f *Fs) MkdirAll(ctx context.Context, name string) error {
+	if err := f.checkKey("mkdir", name, f.directoryFile); err != nil {
+		return err
+	}
+
+	name = f.cleanPath(name)
+	if name == "" {
+		return nil
+	}
+
+	elems := strings.Split(name, pathSeparator)
+	for i := range elems {
+		dirName := strings.Join(elems[:i+1], pathSeparator)
+
+		info, err := f.StatWithContext(ctx, dirName)
+		if err == nil {
+			if !info.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: dirName, Err: fs.ErrExist}
+			}
+			continue
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		if err := f.putDirectoryFile(ctx, dirName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RepairDirectories writes the missing directory files of the named directory and the ones under it,
+// so directories only implied by the keys they hold remain once emptied.
+// It returns the number of directory files written.
+func (f *Fs) RepairDirectories(dirName string) (int, error) {
+	return f.RepairDirectoriesWithContext(context.Background(), dirName)
+}
+
+// RepairDirectoriesWithContext writes the missing directory files of the named directory and the ones under it,
+// so directories only implied by the keys they hold remain once emptied.
+// It returns the number of directory files written.
+func (f *Fs) RepairDirectoriesWithContext(ctx context.Context, dirName string) (int, error) {
+	if err := f.checkKey("mkdir", dirName, f.directoryFile); err != nil {
+		return 0, err
+	}
+
+	keys, err := f.listKeys(ctx, dirName)
+	if err != nil {
+		return 0, err
+	}
+
+	root := f.cleanPath(dirName)
+	dirs := make(map[string]bool)
+
+	for _, key := range keys {
+		name := key.name
+
+		// a directory with its own key, a directory file or a key ending with the separator
+		switch {
+		case strings.HasSuffix(name, pathSeparator):
+			name = strings.TrimSuffix(name, pathSeparator)
+			dirs[name] = true
+		case path.Base(name) == f.directoryFile:
+			name = path.Dir(name)
+			dirs[name] = true
+		}
+
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, found := dirs[dir]; !found {
+				dirs[dir] = false
+			}
+		}
+	}
+
+	missing := make([]string, 0, len(dirs)+1)
+	for dir, hasFile := range dirs {
+		if !hasFile && dir != "." {
+			missing = append(missing, path.Join(root, dir))
+		}
+	}
+
+	// the root of the Fs has no directory file
+	if root != "" && len(keys) > 0 && !dirs["."] {
+		missing = append(missing, root)
+	}
+
+	sort.Strings(missing)
+
+	for i, dir := range missing {
+		if err := f.putDirectoryFile(ctx, dir); err != nil {
+			return i, err
+		}
+	}
+
+	return len(missing), nil
+}
+
+// putDirectoryFile writes the directory file of the named directory.
+func (f *Fs) putDirectoryFile(ctx context.Context, name string) error {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	_, err := f.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name, f.directoryFile)),
+		Body:   bytes.NewReader(nil),
+		ACL:    f.acl,
+	})
+	f.invalidate(f.cleanPath(name))
+
+	return err
+}
+
+// ReadDir reads the named directory
+// and returns a list of directory entries sorted by filename.
+// An object and a prefix with the same name are listed once, as a directory.
+func (f *Fs) ReadDir(dirName string) ([]fs.DirEntry, error) {
+	return f.ReadDirWithContext(context.Background(), dirName)
+}
+
+// ReadDirWithContext reads the named directory
+// and returns a list of directory entries sorted by filename.
+// An object and a prefix with the same name are listed once, as a directory.
+func (f *Fs) ReadDirWithContext(ctx context.Context, dirName string) ([]fs.DirEntry, error) {
+	if vp, ok := f.parseVersionPath(dirName); ok {
+		return f.versionReadDir(ctx, dirName, vp)
+	}
+
+	dirName = f.cleanPath(dirName)
+
+	if entries, found := f.listCache.get(dirName); found {
+		return entries, nil
+	}
+
+	if err := f.statDir(ctx, dirName); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []fs.DirEntry{}, nil
+		}
+		return nil, err
+	}
+
+	current := &Directory{
+		fs:       f,
+		name:     dirName,
+		fileInfo: directoryFileInfo(currentDirName, f.now()),
+		marker:   f.withPrefix(dirName, f.directoryFile),
+	}
+	result := []fs.DirEntry{current}
+
+	hasFiles := false
+
+	lister := newDirLister(f, dirName)
+	err := f.rangeLister(ctx, lister, func(entry fs.DirEntry) error {
+		hasFiles = hasFiles || !entry.IsDir()
+		result = append(result, entry)
+		return nil
+	})
+	current.setListed(lister)
+	truncated := errors.Is(err, ErrListingTruncated)
+	if err != nil && !truncated && !f.partialListing {
+		return nil, err
+	}
+
+	if f.versionBrowsing && hasFiles {
+		result = append(result, &Directory{
+			fs:       f,
+			name:     f.child(dirName, versionsDir),
+			fileInfo: directoryFileInfo(versionsDir, f.now()),
+		})
+	}
+
+	// the key of a file sorts before the prefix of a directory of the same name,
+	// so both may be listed in different pages: the directory wins
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Name() == result[j].Name() {
+			return result[i].IsDir() && !result[j].IsDir()
+		}
+		return result[i].Name() < result[j].Name()
+	})
+
+	result = slices.CompactFunc(result, func(a, b fs.DirEntry) bool { return a.Name() == b.Name() })
+
+	if truncated {
+		return result, err
+	}
+
+	if err != nil {
+		return result, &fs.PathError{Op: "readdir", Path: dirName, Err: fmt.Errorf("%w: %w", ErrIncompleteListing, err)}
+	}
+
+	f.listCache.set(dirName, result)
+
+	return result, nil
+}
+
+// ReadFiles reads the files of the named directory, without its subdirectories,
+// and returns a list of directory entries sorted by filename.
+func (f *Fs) ReadFiles(dirName string) ([]fs.DirEntry, error) {
+	return f.ReadFilesWithContext(context.Background(), dirName)
+}
+
+// ReadFilesWithContext reads the files of the named directory, without its subdirectories,
+// and returns a list of directory entries sorted by filename.
+func (f *Fs) ReadFilesWithContext(ctx context.Context, dirName string) ([]fs.DirEntry, error) {
+	dirName = f.cleanPath(dirName)
+
+	if err := f.statDir(ctx, dirName); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []fs.DirEntry{}, nil
+		}
+		return nil, err
+	}
+
+	result := []fs.DirEntry{}
+	dirs := make(map[string]struct{})
+
+	err := f.rangeDir(ctx, dirName, func(entry fs.DirEntry) error {
+		if entry.IsDir() {
+			dirs[entry.Name()] = struct{}{}
+			return nil
+		}
+		result = append(result, entry)
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrListingTruncated) {
+		return nil, err
+	}
+
+	// as in ReadDir, a file and a directory of the same name resolve to the directory
+	result = slices.DeleteFunc(result, func(entry fs.DirEntry) bool {
+		_, found := dirs[entry.Name()]
+		return found
+	})
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+
+	return result, err
+}
+
+// OpenLatest opens the file of the named directory modified last, without its subdirectories,
+// and returns its name, such as the newest log or snapshot.
+// Files modified at the same time resolve to the greatest name.
+// It returns fs.ErrNotExist when the directory has no files.
+func (f *Fs) OpenLatest(dirName string) (fs.File, string, error) {
+	return f.OpenLatestWithContext(context.Background(), dirName)
+}
+
+// OpenLatestWithContext opens the file of the named directory modified last, without its subdirectories,
+// and returns its name, such as the newest log or snapshot.
+// Files modified at the same time resolve to the greatest name.
+// It returns fs.ErrNotExist when the directory has no files.
+func (f *Fs) OpenLatestWithContext(ctx context.Context, dirName string) (fs.File, string, error) {
+	entries, err := f.ReadFilesWithContext(ctx, dirName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		latest  string
+		modTime time.Time
+	)
+
+	// entries are sorted by name, so later entries win the ties
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, "", err
+		}
+
+		if latest == "" || !info.ModTime().Before(modTime) {
+			latest = entry.Name()
+			modTime = info.ModTime()
+		}
+	}
+
+	if latest == "" {
+		return nil, "", &fs.PathError{Op: "openlatest", Path: dirName, Err: fs.ErrNotExist}
+	}
+
+	name := f.join(f.cleanPath(dirName), latest)
+
+	file, err := f.OpenWithContext(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return file, name, nil
+}
+
+// ReadDirFlat reads every file under the named directory, including nested ones,
+// as a single listing without directories.
+// Entries are named after their path relative to the directory, sorted by name.
+func (f *Fs) ReadDirFlat(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	name = f.cleanPath(name)
+
+	if err := f.statDir(ctx, name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []fs.DirEntry{}, nil
+		}
+		return nil, err
+	}
+
+	prefix := f.withPrefix(name) + pathSeparator
+	if prefix == pathSeparator {
+		prefix = ""
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(f.client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: f.pageSize(),
+	})
+
+	result := []fs.DirEntry{}
+
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if f.pageLimitReached(pages) {
+			return result, &fs.PathError{Op: "readdir", Path: name, Err: ErrListingTruncated}
+		}
+
+		pageCtx, cancelFn := ctx, context.CancelFunc(func() {})
+		if f.timeout > 0 {
+			pageCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		}
+
+		page, err := paginator.NextPage(pageCtx)
+		cancelFn()
+		if err != nil {
+			return nil, mapError(err)
+		}
+
+		for _, obj := range page.Contents {
+			key := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if key == "" || strings.HasSuffix(key, pathSeparator) || path.Base(key) == f.directoryFile {
+				continue
+			}
+
+			result = append(result, &File{
+				fs:   f,
+				info: objectFileInfo(key, obj, f.now),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// ListBySuffix returns the names of the files under the named directory, including nested ones,
+// ending with suffix, sorted.
+// S3 has no suffix filter, the keys are filtered from a single listing without directories.
+func (f *Fs) ListBySuffix(dirName, suffix string) ([]string, error) {
+	return f.ListBySuffixWithContext(context.Background(), dirName, suffix)
+}
+
+// ListBySuffixWithContext returns the names of the files under the named directory, including nested ones,
+// ending with suffix, sorted.
+// S3 has no suffix filter, the keys are filtered from a single listing without directories.
+func (f *Fs) ListBySuffixWithContext(ctx context.Context, dirName, suffix string) ([]string, error) {
+	name := f.cleanPath(dirName)
+
+	prefix := f.withPrefix(name) + pathSeparator
+	if prefix == pathSeparator {
+		prefix = ""
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(f.client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: f.pageSize(),
+	})
+
+	result := []string{}
+
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if f.pageLimitReached(pages) {
+			sort.Strings(result)
+			return result, &fs.PathError{Op: "list", Path: name, Err: ErrListingTruncated}
+		}
+
+		pageCtx, cancelFn := ctx, context.CancelFunc(func() {})
+		if f.timeout > 0 {
+			pageCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		}
+
+		page, err := paginator.NextPage(pageCtx)
+		cancelFn()
+		if err != nil {
+			return nil, mapError(err)
+		}
+
+		for _, obj := range page.Contents {
+			key := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if key == "" || !strings.HasSuffix(key, suffix) || strings.HasSuffix(key, pathSeparator) || path.Base(key) == f.directoryFile {
+				continue
+			}
+
+			if name != "" {
+				key = name + pathSeparator + key
+			}
+			result = append(result, key)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// RangeDir calls fn for each entry of the named directory as listing pages are fetched,
+// without holding the whole listing in memory.
+// Entries are sorted by filename within a page, but the global order is not guaranteed.
+// Unlike ReadDir, the current directory entry "." is not included.
+// If fn returns fs.SkipAll, RangeDir stops and returns nil, any other error is returned.
+func (f *Fs) RangeDir(ctx context.Context, dirName string, fn func(fs.DirEntry) error) error {
+	dirName = f.cleanPath(dirName)
+
+	if err := f.statDir(ctx, dirName); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	err := f.rangeDir(ctx, dirName, fn)
+	if errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+
+	return err
+}
+
+func (f *Fs) rangeDir(ctx context.Context, dirName string, fn func(fs.DirEntry) error) error {
+	return f.rangeLister(ctx, newDirLister(f, dirName), fn)
+}
+
+// rangeLister calls fn for each entry listed by lister, as rangeDir.
+func (f *Fs) rangeLister(ctx context.Context, lister *dirLister, fn func(fs.DirEntry) error) error {
+	for pages := 0; lister.hasMorePages(); pages++ {
+		if f.pageLimitReached(pages) {
+			return &fs.PathError{Op: "readdir", Path: lister.dirName, Err: ErrListingTruncated}
+		}
+
+		// stops between pages once the caller gives up
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// each page has its own timeout, derived from the caller context
+		pageCtx, cancelFn := ctx, context.CancelFunc(func() {})
+		if f.timeout > 0 {
+			pageCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		}
+
+		entries, err := lister.nextPage(pageCtx)
+		cancelFn()
+		if err != nil {
+			return mapError(err)
+		}
+
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (f *Fs) statDir(ctx context.Context, dirName string) error {
+	info, err := f.StatWithContext(ctx, dirName)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return &fs.PathError{Op: "readdir", Path: dirName, Err: ErrNotDirectory}
+	}
+
+	return nil
+}
+
+// Remove removes the named file.
+func (f *Fs) Remove(filename string) error {
+	return f.RemoveWithContext(context.Background(), filename)
+}
+
+// RemoveWithContext removes the named file.
+func (f *Fs) RemoveWithContext(ctx context.Context, fileName string) error {
+	info, err := f.StatWithContext(ctx, fileName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if info.IsDir() {
+		return &fs.PathError{Op: "remove", Path: fileName, Err: ErrIsDirectory}
+	}
+
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	_, err = f.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(fileName)),
+	})
+	f.invalidate(f.cleanPath(fileName))
+	return err
+}
+
+// Rename renames (moves) oldpath to newpath.
+// If newpath already exists and is not a directory, Rename replaces it.
+func (f *Fs) Rename(oldpath, newpath string) error {
+	return f.RenameWithContext(context.Background(), oldpath, newpath)
+}
+
+// RenameWithContext renames (moves) oldpath to newpath.
+// If newpath already exists and is not a directory, Rename replaces it.
+func (f *Fs) RenameWithContext(ctx context.Context, oldpath, newpath string) error {
+	if err := f.checkKey("rename", newpath); err != nil {
+		return err
+	}
+
+	oldInfo, err := f.StatWithContext(ctx, oldpath)
+	if err != nil {
+		return err
+	}
+
+	if oldInfo.IsDir() {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: ErrIsDirectory}
+	}
+
+	newInfo, err := f.StatWithContext(ctx, newpath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if newInfo.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newpath, Err: ErrIsDirectory}
 	}
 
 	if f.timeout > 0 {
@@ -423,13 +1301,170 @@ func (f *Fs) RenameWithContext(ctx context.Context, oldpath, newpath string) err
 	_, err = f.client.CopyObject(ctx, &s3.CopyObjectInput{
 		Bucket:     aws.String(f.bucket),
 		Key:        aws.String(f.withPrefix(newpath)),
-		CopySource: aws.String(path.Join(f.bucket, f.withPrefix(oldpath))),
+		CopySource: aws.String(f.copySource(oldpath)),
+	})
+	f.invalidate(f.cleanPath(newpath))
+	if err != nil {
+		return err
+	}
+
+	// the copy is already done, retrying only the delete of the source
+	_, err = f.renameClient.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(oldpath)),
+	})
+	f.invalidate(f.cleanPath(oldpath))
+	if err != nil {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: fmt.Errorf("%w: %w", ErrRenameCleanupFailed, err)}
+	}
+
+	return nil
+}
+
+// CopyIfNewer copies src to dst, using a server side copy,
+// only if src was modified after dst or dst does not exist.
+// It reports whether the copy happened.
+func (f *Fs) CopyIfNewer(src, dst string) (bool, error) {
+	return f.CopyIfNewerWithContext(context.Background(), src, dst)
+}
+
+// CopyIfNewerWithContext copies src to dst, using a server side copy,
+// only if src was modified after dst or dst does not exist.
+// It reports whether the copy happened.
+func (f *Fs) CopyIfNewerWithContext(ctx context.Context, src, dst string) (bool, error) {
+	if err := f.checkKey("copy", dst); err != nil {
+		return false, err
+	}
+
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	srcHead, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(src)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, &fs.PathError{Op: "copy", Path: src, Err: fs.ErrNotExist}
+		}
+		return false, mapError(err)
+	}
+
+	dstHead, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(dst)),
+	})
+	if err != nil && !isNotFound(err) {
+		return false, mapError(err)
+	}
+
+	if err == nil && !aws.ToTime(srcHead.LastModified).After(aws.ToTime(dstHead.LastModified)) {
+		return false, nil
+	}
+
+	_, err = f.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(f.bucket),
+		Key:        aws.String(f.withPrefix(dst)),
+		CopySource: aws.String(f.copySource(src)),
 	})
+	f.invalidate(f.cleanPath(dst))
+	if err != nil {
+		return false, mapError(err)
+	}
+
+	return true, nil
+}
+
+// UpdateMetadata replaces the metadata and content type of the named file,
+// using a server side copy of the object onto itself, so the content is not transferred.
+// An empty contentType keeps the current one. The other HTTP headers, storage class,
+// encryption settings and tags are kept, the ACL is the one set by WithACL, as for any copy.
+func (f *Fs) UpdateMetadata(ctx context.Context, name string, meta map[string]string, contentType string) error {
+	head, err := f.headFile(ctx, "headers", name)
 	if err != nil {
 		return err
 	}
 
-	return f.RemoveWithContext(ctx, oldpath)
+	return f.copyOntoItself(ctx, name, head, meta, contentType)
+}
+
+// maxUniqueNames is the number of suffixes tried by UniqueName.
+const maxUniqueNames = 1000
+
+// UniqueName returns name if it does not exist, or the first free name
+// with " (1)", " (2)", ... inserted before the extension, as file managers do.
+// fs.ErrExist is returned when the first 1000 suffixes are taken.
+func (f *Fs) UniqueName(name string) (string, error) {
+	return f.UniqueNameWithContext(context.Background(), name)
+}
+
+// UniqueNameWithContext returns name if it does not exist, or the first free name
+// with " (1)", " (2)", ... inserted before the extension, as file managers do.
+// fs.ErrExist is returned when the first 1000 suffixes are taken.
+func (f *Fs) UniqueNameWithContext(ctx context.Context, name string) (string, error) {
+	name = f.cleanPath(name)
+
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	if ext == base {
+		// dot files have no extension
+		ext = ""
+	}
+	stem := strings.TrimSuffix(base, ext)
+
+	// the name itself, then each suffix
+	for i := 0; i <= maxUniqueNames; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s%s (%d)%s", dir, stem, i, ext)
+		}
+
+		_, err := f.StatWithContext(ctx, candidate)
+		if errors.Is(err, fs.ErrNotExist) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", &fs.PathError{Op: "uniquename", Path: name, Err: fs.ErrExist}
+}
+
+// Touch creates the named file empty, or updates the modification time of an existing file,
+// keeping its content, metadata and headers with a server side copy onto itself.
+func (f *Fs) Touch(ctx context.Context, name string) error {
+	if err := f.checkKey("touch", name); err != nil {
+		return err
+	}
+
+	head, err := f.headFile(ctx, "touch", name)
+	if err == nil {
+		return f.copyOntoItself(ctx, name, head, head.Metadata, "")
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	_, err = f.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(f.bucket),
+		Key:         aws.String(f.withPrefix(name)),
+		Body:        bytes.NewReader(nil),
+		ACL:         f.acl,
+		ContentType: f.contentType(name),
+	})
+	f.invalidate(f.cleanPath(name))
+
+	return mapError(err)
 }
 
 // RemoveDir removes an empty directory.
@@ -438,23 +1473,205 @@ func (f *Fs) RemoveDir(name string) error {
 }
 
 // RemoveDirWithContext removes an empty directory.
+// The directory file is not listed, so a directory holding only it is empty.
+// Removing a missing directory is not an error.
 func (f *Fs) RemoveDirWithContext(ctx context.Context, name string) error {
-	entries, err := f.ReadDirWithContext(ctx, name)
-	if err != nil {
+	name = f.cleanPath(name)
+
+	if err := f.statDir(ctx, name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// nothing to remove
+			return nil
+		}
+		return err
+	}
+
+	empty := true
+
+	err := f.rangeDir(ctx, name, func(fs.DirEntry) error {
+		empty = false
+		return fs.SkipAll
+	})
+	if err != nil && !errors.Is(err, fs.SkipAll) {
 		return err
 	}
 
-	if len(entries) == 1 && entries[0].Name() == currentDirName {
-		return f.Remove(path.Join(name, f.directoryFile))
+	if !empty {
+		return &fs.PathError{Op: "remove", Path: name, Err: ErrNotEmpty}
+	}
+
+	return f.RemoveWithContext(ctx, f.join(name, f.directoryFile))
+}
+
+// PresignGetURL returns a URL allowing to download the named file
+// without credentials until it expires.
+// It requires the Fs to be created with a *s3.Client.
+func (f *Fs) PresignGetURL(ctx context.Context, name string, expires time.Duration) (string, error) {
+	client, err := f.presignClient(ctx, name, false)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := client.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name)),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+
+	return req.URL, nil
+}
+
+// PresignPutURL returns a URL allowing to upload the named file
+// without credentials until it expires.
+// It requires the Fs to be created with a *s3.Client.
+func (f *Fs) PresignPutURL(ctx context.Context, name string, expires time.Duration) (string, error) {
+	if err := f.checkKey("presign", name); err != nil {
+		return "", err
+	}
+
+	client, err := f.presignClient(ctx, name, true)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := client.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name)),
+		ACL:    f.acl,
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+
+	return req.URL, nil
+}
+
+func (f *Fs) presignClient(ctx context.Context, name string, allowNotExist bool) (*s3.PresignClient, error) {
+	client, ok := baseClient(f.client).(*s3.Client)
+	if !ok {
+		return nil, fmt.Errorf("presign requires a *s3.Client: %w", errors.ErrUnsupported)
+	}
+
+	info, err := f.StatWithContext(ctx, name)
+	if err != nil && !(allowNotExist && errors.Is(err, fs.ErrNotExist)) {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "presign", Path: name, Err: ErrIsDirectory}
 	}
 
-	return fmt.Errorf("directory not empty: %w", fs.ErrInvalid)
+	// the presign client is built from the base client, so the options of the Fs are set again
+	return s3.NewPresignClient(client, func(o *s3.PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, f.s3Options...)
+	}), nil
 }
 
 func (f *Fs) withPrefix(name ...string) string {
-	p := path.Join(append([]string{f.prefix}, name...)...)
+	return f.join(append([]string{f.prefix}, name...)...)
+}
+
+// join joins the names into a cleaned name,
+// raw names are joined with a single separator and kept as is.
+func (f *Fs) join(name ...string) string {
+	if f.rawKeys {
+		parts := make([]string, 0, len(name))
+		for _, s := range name {
+			if s = f.cleanPath(s); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, pathSeparator)
+	}
+
+	return cleanPath(path.Join(name...))
+}
+
+// child returns the name of the entry base of the directory dir, both cleaned.
+// Raw names are concatenated, so "a//b" is not merged with "a/b".
+func (f *Fs) child(dir, base string) string {
+	if !f.rawKeys {
+		return path.Join(dir, base)
+	}
+
+	if dir == "" {
+		return base
+	}
 
-	return cleanPath(p)
+	return dir + pathSeparator + base
+}
+
+// dir returns the parent directory of the cleaned name, "" for the root.
+// The parent of a raw name is the name up to its last separator, so "a//b" is in "a/".
+func (f *Fs) dir(name string) string {
+	if !f.rawKeys {
+		return parentDir(name)
+	}
+
+	i := strings.LastIndex(name, pathSeparator)
+	if i < 0 {
+		return ""
+	}
+
+	return name[:i]
+}
+
+// parentDir returns the parent directory of the cleaned name, "" for the root.
+func parentDir(name string) string {
+	return cleanPath(path.Dir(name))
+}
+
+// copySource returns the CopySource of the named file.
+func (f *Fs) copySource(name string) string {
+	return copySource(f.bucket, f.withPrefix(name))
+}
+
+// copySource returns the CopySource of the key, which S3 URL-decodes,
+// so keys with characters such as "%", "+" or spaces are escaped.
+// Each segment is escaped on its own and the key is not cleaned,
+// keeping keys like "a//b" intact.
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, pathSeparator)
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(s), "+", "%2B")
+	}
+
+	return bucket + pathSeparator + strings.Join(segments, pathSeparator)
+}
+
+// pageSize returns the MaxKeys of listing pages, nil for the S3 default.
+func (f *Fs) pageSize() *int32 {
+	if f.maxKeys == 0 {
+		return nil
+	}
+	return aws.Int32(f.maxKeys)
+}
+
+// pageLimitReached reports whether a listing of pages pages reached WithMaxListPages.
+func (f *Fs) pageLimitReached(pages int) bool {
+	return f.maxListPages > 0 && pages >= f.maxListPages
+}
+
+// invalidate removes the cached stats and listings affected by a change of the cleaned names.
+func (f *Fs) invalidate(names ...string) {
+	f.statCache.invalidate(names...)
+	f.listCache.invalidate(names...)
+}
+
+// cleanPath returns the name of a file relative to the root,
+// normalized unless raw keys are used.
+func (f *Fs) cleanPath(name string) string {
+	if !f.rawKeys {
+		return cleanPath(name)
+	}
+
+	if name == currentDirName {
+		return ""
+	}
+
+	return name
 }
 
 func cleanPath(name string) string {
@@ -485,3 +1702,11 @@ func getOrElse[T any](v *T, fallback func() T) T {
 }
 
 func zeroInt64() int64 { return 0 }
+
+// nonEmpty returns nil for empty strings, leaving the field unset.
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -1,10 +1,35 @@
 package s3fs
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/jacoelho/s3fs/s3fstest"
 )
 
+func newTestFs(t *testing.T, opts ...Option) (*Fs, *s3fstest.Client) {
+	t.Helper()
+
+	client := s3fstest.New()
+	client.CreateBucket("test")
+
+	return New(client, "test", opts...), client
+}
+
 func TestClean(t *testing.T) {
 	tests := []struct {
 		name string
@@ -36,3 +61,1985 @@ func TestClean(t *testing.T) {
 		})
 	}
 }
+
+func TestRawKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		raw  string
+	}{
+		{
+			name: "a/b",
+			key:  "prefix/a/b",
+			raw:  "prefix/a/b",
+		},
+		{
+			name: "//a//b",
+			key:  "prefix/a/b",
+			raw:  "prefix///a//b",
+		},
+		{
+			name: "a/./b",
+			key:  "prefix/a/b",
+			raw:  "prefix/a/./b",
+		},
+		{
+			name: "a/../b",
+			key:  "prefix/b",
+			raw:  "prefix/a/../b",
+		},
+		{
+			name: "a/b/",
+			key:  "prefix/a/b",
+			raw:  "prefix/a/b/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []struct {
+				want string
+				opts []Option
+			}{
+				{want: tt.key, opts: []Option{WithPrefix("prefix")}},
+				{want: tt.raw, opts: []Option{WithPrefix("prefix"), WithRawKeys()}},
+			} {
+				fsys, client := newTestFs(t, c.opts...)
+
+				w, err := fsys.Create(tt.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				if got := client.Keys("test"); len(got) != 1 || got[0] != c.want {
+					t.Errorf("keys = %v, want [%s]", got, c.want)
+				}
+
+				if _, err := fsys.Stat(tt.name); err != nil {
+					t.Errorf("Stat() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRawKeysReadDir(t *testing.T) {
+	fsys, client := newTestFs(t, WithRawKeys())
+	client.Put("test", "x/../y.txt", []byte("y"))
+	client.Put("test", "x//b.txt", []byte("b"))
+
+	names := func(entries []fs.DirEntry) []string {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	entries, err := fsys.ReadDir("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "x//" is listed by the directory "x/"
+	if got := names(entries); !slices.Equal(got, []string{".", ".."}) {
+		t.Fatalf("ReadDir(x) = %v, want [. ..]", got)
+	}
+
+	// the entry lists the prefix it was listed as, not the cleaned one
+	dir, ok := entries[1].(fs.ReadDirFile)
+	if !ok {
+		t.Fatalf("entry %T is not a fs.ReadDirFile", entries[1])
+	}
+
+	sub, err := dir.ReadDir(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(sub); !slices.Equal(got, []string{"y.txt"}) {
+		t.Errorf("ReadDir(x/..) = %v, want [y.txt]", got)
+	}
+
+	entries, err = fsys.ReadDir("x/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(entries); !slices.Equal(got, []string{".", "b.txt"}) {
+		t.Errorf("ReadDir(x/) = %v, want [. b.txt]", got)
+	}
+}
+
+func TestRawKeysRoundTrip(t *testing.T) {
+	fsys, client := newTestFs(t, WithRawKeys(), WithStatCache(time.Minute), WithListCache(time.Minute))
+
+	f, err := fsys.Create("a//b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Keys("test"); len(got) != 1 || got[0] != "a//b.txt" {
+		t.Fatalf("keys = %v, want [a//b.txt]", got)
+	}
+
+	r, err := fsys.OpenReader("a//b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+
+	if string(got) != "content" {
+		t.Errorf("content = %q, want %q", got, "content")
+	}
+
+	if _, err := fsys.OpenReader("a/b.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenReader(a/b.txt) error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	// "a//b.txt" is listed in the directory "a/", whose cached listing is invalidated
+	readDirNames := func() []string {
+		t.Helper()
+
+		entries, err := fsys.ReadDir("a/")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	if got := readDirNames(); !slices.Equal(got, []string{".", "b.txt"}) {
+		t.Fatalf("ReadDir(a/) = %v, want [. b.txt]", got)
+	}
+
+	if err := fsys.Rename("a//b.txt", "a//c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Keys("test"); len(got) != 1 || got[0] != "a//c.txt" {
+		t.Fatalf("keys = %v, want [a//c.txt]", got)
+	}
+
+	if got := readDirNames(); !slices.Equal(got, []string{".", "c.txt"}) {
+		t.Fatalf("ReadDir(a/) = %v, want [. c.txt]", got)
+	}
+
+	if err := fsys.Remove("a//c.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Keys("test"); len(got) != 0 {
+		t.Fatalf("keys = %v, want none", got)
+	}
+
+	if got := readDirNames(); len(got) != 0 {
+		t.Errorf("ReadDir(a/) = %v, want none", got)
+	}
+
+	if _, err := fsys.Stat("a//c.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(a//c.txt) error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestDotKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		raw  string
+	}{
+		{
+			name: "a/.hidden",
+			key:  "prefix/a/.hidden",
+			raw:  "prefix/a/.hidden",
+		},
+		{
+			name: "weird/./name",
+			key:  "prefix/weird/name",
+			raw:  "prefix/weird/./name",
+		},
+		{
+			name: "name.",
+			key:  "prefix/name.",
+			raw:  "prefix/name.",
+		},
+		{
+			name: "dir/..name",
+			key:  "prefix/dir/..name",
+			raw:  "prefix/dir/..name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, c := range []struct {
+				want string
+				opts []Option
+			}{
+				{want: tt.key, opts: []Option{WithPrefix("prefix")}},
+				{want: tt.raw, opts: []Option{WithPrefix("prefix"), WithRawKeys()}},
+			} {
+				fsys, client := newTestFs(t, c.opts...)
+				client.Put("test", c.want, []byte(c.want))
+
+				f, err := fsys.OpenReader(tt.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := io.ReadAll(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = f.Close()
+
+				if string(got) != c.want {
+					t.Errorf("content = %q, want %q", got, c.want)
+				}
+
+				in := client.Calls()[0].Input.(*s3.GetObjectInput)
+				if aws.ToString(in.Key) != c.want {
+					t.Errorf("GetObject key = %q, want %q", aws.ToString(in.Key), c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSpecialCharacterKeys(t *testing.T) {
+	names := []string{"föö bar+#1.txt", "100% a%2Fb.txt", "dir/?a=b&c;d.txt"}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			fsys, client := newTestFs(t, WithPrefix("prefix"))
+
+			f, err := fsys.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Write([]byte(name)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := client.Keys("test"); len(got) != 1 || got[0] != "prefix/"+name {
+				t.Fatalf("keys = %q, want [%q]", got, "prefix/"+name)
+			}
+
+			info, err := fsys.Stat(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Size() != int64(len(name)) {
+				t.Errorf("Size() = %d, want %d", info.Size(), len(name))
+			}
+
+			if err := fsys.Rename(name, "renamed/"+name); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := client.Keys("test"); len(got) != 1 || got[0] != "prefix/renamed/"+name {
+				t.Fatalf("keys after rename = %q, want [%q]", got, "prefix/renamed/"+name)
+			}
+
+			got, err := fs.ReadFile(fsys, "renamed/"+name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != name {
+				t.Errorf("content = %q, want %q", got, name)
+			}
+
+			if err := fsys.Remove("renamed/" + name); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := client.Keys("test"); len(got) != 0 {
+				t.Errorf("keys after remove = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestRangeDirStopsEarly(t *testing.T) {
+	fsys, client := newTestFs(t)
+	for i := 0; i < 2500; i++ {
+		client.Put("test", fmt.Sprintf("dir/file_%04d", i), []byte("data"))
+	}
+
+	var names []string
+	err := fsys.RangeDir(context.Background(), "dir", func(entry fs.DirEntry) error {
+		names = append(names, entry.Name())
+		if len(names) == 10 {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 10 {
+		t.Fatalf("got %d entries, want 10", len(names))
+	}
+
+	if names[0] != "file_0000" || names[9] != "file_0009" {
+		t.Errorf("unexpected entries %v", names)
+	}
+
+	for _, call := range client.Calls() {
+		if in, ok := call.Input.(*s3.ListObjectsV2Input); ok && in.ContinuationToken != nil {
+			t.Errorf("unexpected page fetched with token %s", *in.ContinuationToken)
+		}
+	}
+}
+
+func TestRangeDirAllPages(t *testing.T) {
+	fsys, client := newTestFs(t)
+	for i := 0; i < 2500; i++ {
+		client.Put("test", fmt.Sprintf("dir/file_%04d", i), []byte("data"))
+	}
+	client.Put("test", "dir/sub/file", []byte("data"))
+
+	count := 0
+	err := fsys.RangeDir(context.Background(), "dir", func(entry fs.DirEntry) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2501 {
+		t.Errorf("got %d entries, want 2501", count)
+	}
+}
+
+func TestBucketNotFound(t *testing.T) {
+	fsys := New(s3fstest.New(), "missing")
+
+	_, err := fsys.Stat("file.txt")
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("Stat() error = %v, want %v", err, ErrBucketNotFound)
+	}
+
+	_, err = fsys.ReadDir(".")
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("ReadDir() error = %v, want %v", err, ErrBucketNotFound)
+	}
+
+	_, err = fsys.OpenReaderWithContext(context.Background(), "file.txt")
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("OpenReader() error = %v, want %v", err, ErrBucketNotFound)
+	}
+
+	_, _, err = fsys.ReadPart("file.txt", 1)
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("ReadPart() error = %v, want %v", err, ErrBucketNotFound)
+	}
+
+	_, err = fsys.DownloadTo(context.Background(), "file.txt", &manager.WriteAtBuffer{})
+	if !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("DownloadTo() error = %v, want %v", err, ErrBucketNotFound)
+	}
+}
+
+func TestCopyIfNewer(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		srcModTime time.Time
+		dstModTime time.Time
+		name       string
+		dstExists  bool
+		wantCopied bool
+	}{
+		{
+			name:       "newer source",
+			srcModTime: now.Add(time.Hour),
+			dstModTime: now,
+			dstExists:  true,
+			wantCopied: true,
+		},
+		{
+			name:       "older source",
+			srcModTime: now,
+			dstModTime: now.Add(time.Hour),
+			dstExists:  true,
+			wantCopied: false,
+		},
+		{
+			name:       "missing destination",
+			srcModTime: now,
+			wantCopied: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+
+			if tt.dstExists {
+				client.Now = func() time.Time { return tt.dstModTime }
+				client.Put("test", "dst.txt", []byte("old"))
+			}
+
+			client.Now = func() time.Time { return tt.srcModTime }
+			client.Put("test", "src.txt", []byte("new"))
+
+			copied, err := fsys.CopyIfNewer("src.txt", "dst.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if copied != tt.wantCopied {
+				t.Errorf("CopyIfNewer() = %v, want %v", copied, tt.wantCopied)
+			}
+
+			if got := client.Count("CopyObject"); (got == 1) != tt.wantCopied {
+				t.Errorf("CopyObject calls = %d", got)
+			}
+
+			obj, _ := client.Object("test", "dst.txt")
+			if want := map[bool]string{true: "new", false: "old"}[tt.wantCopied]; string(obj.Data) != want {
+				t.Errorf("destination = %q, want %q", obj.Data, want)
+			}
+		})
+	}
+}
+
+func TestCopyIfNewerMissingSource(t *testing.T) {
+	fsys, _ := newTestFs(t)
+
+	_, err := fsys.CopyIfNewer("src.txt", "dst.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("CopyIfNewer() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestStatCache(t *testing.T) {
+	now := time.Now()
+	fsys, client := newTestFs(t, WithStatCache(time.Minute), WithClock(func() time.Time { return now }))
+	client.Put("test", "file", []byte("content"))
+
+	for i := 0; i < 3; i++ {
+		if _, err := fsys.Stat("file"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := client.Count("ListObjectsV2"); got != 1 {
+		t.Fatalf("ListObjectsV2 calls = %d, want 1", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, err := fsys.Stat("file"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Count("ListObjectsV2"); got != 2 {
+		t.Fatalf("ListObjectsV2 calls after ttl = %d, want 2", got)
+	}
+}
+
+func TestListCache(t *testing.T) {
+	now := time.Now()
+	fsys, client := newTestFs(t, WithListCache(time.Minute), WithClock(func() time.Time { return now }))
+	client.Put("test", "dir/a", []byte("content"))
+
+	readDir := func() []string {
+		t.Helper()
+
+		entries, err := fsys.ReadDir("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	readDir()
+	client.Reset()
+
+	if got := readDir(); fmt.Sprint(got) != "[. a]" {
+		t.Errorf("ReadDir() = %v, want [. a]", got)
+	}
+
+	if got := client.Count("ListObjectsV2"); got != 0 {
+		t.Errorf("ListObjectsV2 calls = %d, want 0", got)
+	}
+
+	f, err := fsys.Create("dir/sub/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readDir(); fmt.Sprint(got) != "[. a sub]" {
+		t.Errorf("ReadDir() after create = %v, want [. a sub]", got)
+	}
+
+	client.Put("test", "dir/c", nil)
+	now = now.Add(2 * time.Minute)
+
+	if got := readDir(); fmt.Sprint(got) != "[. a c sub]" {
+		t.Errorf("ReadDir() after ttl = %v, want [. a c sub]", got)
+	}
+}
+
+func TestStatCacheInvalidatedOnRemove(t *testing.T) {
+	fsys, client := newTestFs(t, WithStatCache(time.Minute))
+	client.Put("test", "dir/file", []byte("content"))
+
+	if _, err := fsys.Stat("dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.Stat("dir/file"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.Remove("dir/file"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.Stat("dir/file"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Stat() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if _, err := fsys.Stat("dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Stat() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestStatCacheInvalidatedOnCreate(t *testing.T) {
+	fsys, client := newTestFs(t, WithStatCache(time.Minute))
+	client.Put("test", "file", []byte("content"))
+
+	if _, err := fsys.Stat("file"); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("new content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := fsys.Stat("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := info.Size(), int64(len("new content")); got != want {
+		t.Fatalf("Size() = %d, want %d", got, want)
+	}
+}
+
+func TestStatCacheEviction(t *testing.T) {
+	c := newStatCache(time.Minute, 2)
+
+	c.set("a", regularFileInfo("a", 0, time.Time{}))
+	c.set("b", regularFileInfo("b", 0, time.Time{}))
+
+	// a is now the most recently used
+	if _, found := c.get("a"); !found {
+		t.Fatal("a not found")
+	}
+
+	c.set("c", regularFileInfo("c", 0, time.Time{}))
+
+	if _, found := c.get("b"); found {
+		t.Fatal("b should have been evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, found := c.get(key); !found {
+			t.Fatalf("%s not found", key)
+		}
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "dir/file", []byte("content"))
+
+	err := fsys.UpdateMetadata(context.Background(), "dir/file", map[string]string{"owner": "me"}, "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String("test"),
+		Key:    aws.String("dir/file"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := aws.ToString(head.ContentType); got != "text/plain" {
+		t.Errorf("ContentType = %q, want %q", got, "text/plain")
+	}
+
+	if got := head.Metadata["owner"]; got != "me" {
+		t.Errorf("Metadata[owner] = %q, want %q", got, "me")
+	}
+
+	obj, _ := client.Object("test", "dir/file")
+	if got := string(obj.Data); got != "content" {
+		t.Errorf("content = %q, want %q", got, "content")
+	}
+}
+
+func TestUpdateMetadataKeepsHeaders(t *testing.T) {
+	fsys, client := newTestFs(t)
+
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:             aws.String("test"),
+		Key:                aws.String("file"),
+		Body:               bytes.NewReader([]byte("content")),
+		ContentType:        aws.String("text/csv"),
+		CacheControl:       aws.String("max-age=60"),
+		ContentDisposition: aws.String(`attachment; filename="file.csv"`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.UpdateMetadata(context.Background(), "file", map[string]string{"owner": "me"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, _ := client.Object("test", "file")
+	if aws.ToString(obj.ContentType) != "text/csv" || aws.ToString(obj.CacheControl) != "max-age=60" ||
+		aws.ToString(obj.ContentDisposition) != `attachment; filename="file.csv"` {
+		t.Errorf("headers = (%q, %q, %q), want the ones before the update",
+			aws.ToString(obj.ContentType), aws.ToString(obj.CacheControl), aws.ToString(obj.ContentDisposition))
+	}
+
+	if got := client.Count("HeadObject"); got != 1 {
+		t.Errorf("HeadObject calls = %d, want 1", got)
+	}
+}
+
+func TestUpdateMetadataInvalid(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "dir/file", []byte("content"))
+
+	if err := fsys.UpdateMetadata(context.Background(), "dir", nil, "text/plain"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("UpdateMetadata() error = %v, want %v", err, fs.ErrInvalid)
+	}
+
+	if err := fsys.UpdateMetadata(context.Background(), "missing", nil, "text/plain"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("UpdateMetadata() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if got := client.Count("CopyObject"); got != 0 {
+		t.Errorf("CopyObject calls = %d, want 0", got)
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	fsys, client := newTestFs(t)
+	for _, key := range []string{"dir/file.txt", "dir/file (1).txt", "dir/.env", "dir/sub/x"} {
+		client.Put("test", key, nil)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "dir/new.txt", want: "dir/new.txt"},
+		{name: "dir/file.txt", want: "dir/file (2).txt"},
+		{name: "dir/.env", want: "dir/.env (1)"},
+		{name: "dir/sub", want: "dir/sub (1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fsys.UniqueName(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("UniqueName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueNameExhausted(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file.txt", nil)
+	for i := 1; i < maxUniqueNames; i++ {
+		client.Put("test", fmt.Sprintf("file (%d).txt", i), nil)
+	}
+
+	// the last suffix is tried
+	got, err := fsys.UniqueName("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := fmt.Sprintf("file (%d).txt", maxUniqueNames); got != want {
+		t.Errorf("UniqueName() = %q, want %q", got, want)
+	}
+
+	client.Put("test", got, nil)
+
+	if _, err := fsys.UniqueName("file.txt"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("UniqueName() error = %v, want %v", err, fs.ErrExist)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	fsys, client := newTestFs(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.Now = func() time.Time { return now }
+
+	if err := fsys.Touch(ctx, "lock"); err != nil {
+		t.Fatal(err)
+	}
+
+	first, found := client.Object("test", "lock")
+	if !found || len(first.Data) != 0 {
+		t.Fatalf("object = (%d bytes, %v), want (0, true)", len(first.Data), found)
+	}
+
+	now = now.Add(time.Minute)
+
+	if err := fsys.Touch(ctx, "lock"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := fsys.Stat("lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.ModTime().After(first.LastModified) {
+		t.Errorf("ModTime() = %v, want after %v", info.ModTime(), first.LastModified)
+	}
+
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String("test"),
+		Key:          aws.String("dir/file"),
+		Body:         bytes.NewReader([]byte("content")),
+		CacheControl: aws.String("no-cache"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.Reset()
+
+	if err := fsys.Touch(ctx, "dir/file"); err != nil {
+		t.Fatal(err)
+	}
+
+	if obj, _ := client.Object("test", "dir/file"); string(obj.Data) != "content" || aws.ToString(obj.CacheControl) != "no-cache" {
+		t.Errorf("object = (%q, %q), want (%q, %q)", obj.Data, aws.ToString(obj.CacheControl), "content", "no-cache")
+	}
+
+	// an existing file takes a HeadObject and the copy
+	if got := len(client.Calls()); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+
+	if err := fsys.Touch(ctx, "dir"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Touch(dir) error = %v, want %v", err, fs.ErrInvalid)
+	}
+}
+
+// deadlineClient fails deletes issued without a deadline.
+type deadlineClient struct {
+	*s3fstest.Client
+}
+
+func (c deadlineClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("context without deadline")
+	}
+	return c.Client.DeleteObject(ctx, in, optFns...)
+}
+
+func TestRemoveDirWithContext(t *testing.T) {
+	client := s3fstest.New()
+	client.CreateBucket("test")
+	fsys := New(deadlineClient{client}, "test")
+
+	if _, err := fsys.CreateDir("dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := fsys.RemoveDirWithContext(ctx, "dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := client.Keys("test"); len(keys) != 0 {
+		t.Errorf("keys = %v, want none", keys)
+	}
+}
+
+func TestRemoveDir(t *testing.T) {
+	tests := []struct {
+		wantErr error
+		name    string
+		dir     string
+		keys    []string
+	}{
+		{
+			name: "only directory file",
+			dir:  "dir",
+			keys: []string{"dir/.keep"},
+		},
+		{
+			name:    "implicit directory",
+			dir:     "dir",
+			keys:    []string{"dir/file"},
+			wantErr: ErrDirectoryNotEmpty,
+		},
+		{
+			name:    "directory file and nested directory",
+			dir:     "dir",
+			keys:    []string{"dir/.keep", "dir/sub/.keep"},
+			wantErr: ErrDirectoryNotEmpty,
+		},
+		{
+			name: "missing directory",
+			dir:  "missing",
+			keys: []string{"dir/.keep"},
+		},
+		{
+			name:    "file",
+			dir:     "dir/file",
+			keys:    []string{"dir/file"},
+			wantErr: fs.ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+			for _, key := range tt.keys {
+				client.Put("test", key, nil)
+			}
+
+			err := fsys.RemoveDir(tt.dir)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoveDir() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil {
+				if _, err := fsys.Stat(tt.dir); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Stat() error = %v, want %v", err, fs.ErrNotExist)
+				}
+			}
+		})
+	}
+}
+
+func TestDirectoryModTime(t *testing.T) {
+	listTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys, client := newTestFs(t, WithClock(func() time.Time { return listTime }))
+
+	markerTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fileTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	client.Now = func() time.Time { return markerTime }
+	client.Put("test", "dir/.keep", nil)
+	client.Now = func() time.Time { return fileTime }
+	client.Put("test", "dir/file", nil)
+	client.Put("test", "implicit/file", nil)
+
+	// the directory file is in the listing of the directory itself
+	entries, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entries[0].Name() != currentDirName || !info.ModTime().Equal(markerTime) {
+		t.Errorf("%s ModTime() = %v, want %v", entries[0].Name(), info.ModTime(), markerTime)
+	}
+
+	// without a directory file, the newest file gives the time
+	entries, err = fsys.ReadDir("implicit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.ModTime().Equal(fileTime) {
+		t.Errorf("implicit %s ModTime() = %v, want %v", entries[0].Name(), info.ModTime(), fileTime)
+	}
+
+	f, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.(fs.ReadDirFile).ReadDir(-1); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := info.ModTime(); !got.Equal(markerTime) {
+		t.Errorf("Stat() after ReadDir() ModTime() = %v, want %v", got, markerTime)
+	}
+
+	// subdirectories are listed as prefixes, without a time to take
+	entries, err = fsys.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries[1:] {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.ModTime(); !got.Equal(listTime) {
+			t.Errorf("%s ModTime() = %v, want %v", entry.Name(), got, listTime)
+		}
+	}
+
+	if got := client.Count("HeadObject"); got != 0 {
+		t.Errorf("HeadObject calls = %d, want 0", got)
+	}
+}
+
+// pageContextClient records the context of each listed page, failing when it is done,
+// and calls cancel after the page cancelAfter.
+type pageContextClient struct {
+	*s3fstest.Client
+	cancel      context.CancelFunc
+	contexts    []context.Context
+	cancelAfter int
+}
+
+func (c *pageContextClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.contexts = append(c.contexts, ctx)
+	if len(c.contexts) == c.cancelAfter {
+		c.cancel()
+	}
+
+	return c.Client.ListObjectsV2(ctx, in, optFns...)
+}
+
+func TestReadDirPageContext(t *testing.T) {
+	tests := []struct {
+		wantErr     error
+		name        string
+		wantPages   int
+		cancelAfter int
+	}{
+		// the stat, then 3 pages, each with its own timeout
+		{name: "timeout per page", wantPages: 4},
+		// the stat, then the first page
+		{name: "canceled between pages", cancelAfter: 2, wantPages: 2, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := s3fstest.New()
+			for i := 0; i < 2500; i++ {
+				mock.Put("test", fmt.Sprintf("dir/file%04d", i), nil)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			client := &pageContextClient{Client: mock, cancel: cancel, cancelAfter: tt.cancelAfter}
+			fsys := New(client, "test", WithTimeout(time.Minute))
+
+			_, err := fsys.ReadDirWithContext(ctx, "dir")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadDirWithContext() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(client.contexts) != tt.wantPages {
+				t.Errorf("ListObjectsV2 calls = %d, want %d", len(client.contexts), tt.wantPages)
+			}
+
+			// every page context is released once ReadDir returns
+			for i, pageCtx := range client.contexts {
+				if pageCtx.Err() == nil {
+					t.Errorf("page %d context not released", i)
+				}
+			}
+		})
+	}
+}
+
+// failingPageClient fails the listing pages after the first one.
+type failingPageClient struct {
+	*s3fstest.Client
+}
+
+func (c failingPageClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if in.ContinuationToken != nil {
+		return nil, s3fstest.Error("ListObjectsV2", http.StatusInternalServerError, &smithy.GenericAPIError{Code: "InternalError"})
+	}
+
+	return c.Client.ListObjectsV2(ctx, in, optFns...)
+}
+
+func TestReadDirPartialListing(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantNames []string
+	}{
+		{
+			name: "aborted",
+			opts: []Option{WithMaxKeys(2)},
+		},
+		{
+			name:      "partial",
+			opts:      []Option{WithMaxKeys(2), WithPartialListing(), WithListCache(time.Minute)},
+			wantNames: []string{".", "a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := s3fstest.New()
+			for _, key := range []string{"dir/a", "dir/b", "dir/c", "dir/d"} {
+				client.Put("test", key, nil)
+			}
+			fsys := New(failingPageClient{client}, "test", tt.opts...)
+
+			for i := 0; i < 2; i++ {
+				entries, err := fsys.ReadDir("dir")
+
+				var apiErr smithy.APIError
+				if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "InternalError" {
+					t.Fatalf("ReadDir() error = %v, want InternalError", err)
+				}
+
+				if got := errors.Is(err, ErrIncompleteListing); got != (tt.wantNames != nil) {
+					t.Errorf("ReadDir() error = %v, matches ErrIncompleteListing %v", err, got)
+				}
+
+				var names []string
+				for _, e := range entries {
+					names = append(names, e.Name())
+				}
+
+				if !slices.Equal(names, tt.wantNames) {
+					t.Errorf("ReadDir() = %v, want %v", names, tt.wantNames)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxListPages(t *testing.T) {
+	fsys, client := newTestFs(t, WithMaxKeys(2), WithMaxListPages(2))
+	for _, key := range []string{"dir/a", "dir/b", "dir/c", "dir/d", "dir/e"} {
+		client.Put("test", key, nil)
+	}
+
+	names := func(entries []fs.DirEntry) []string {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+
+	tests := []struct {
+		list      func() ([]string, error)
+		name      string
+		wantNames []string
+	}{
+		{
+			name: "read dir",
+			list: func() ([]string, error) {
+				entries, err := fsys.ReadDir("dir")
+				return names(entries), err
+			},
+			wantNames: []string{".", "a", "b", "c", "d"},
+		},
+		{
+			name: "read files",
+			list: func() ([]string, error) {
+				entries, err := fsys.ReadFiles("dir")
+				return names(entries), err
+			},
+			wantNames: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "read dir flat",
+			list: func() ([]string, error) {
+				entries, err := fsys.ReadDirFlat(context.Background(), "dir")
+				return names(entries), err
+			},
+			wantNames: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "list by suffix",
+			list: func() ([]string, error) {
+				return fsys.ListBySuffix("dir", "")
+			},
+			wantNames: []string{"dir/a", "dir/b", "dir/c", "dir/d"},
+		},
+		{
+			name: "walk dir",
+			list: func() ([]string, error) {
+				var names []string
+				err := fsys.WalkDir("dir", func(p string, _ fs.DirEntry, err error) error {
+					names = append(names, p)
+					return err
+				})
+				return names, err
+			},
+			wantNames: []string{"dir", "dir/a", "dir/b", "dir/c", "dir/d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.Reset()
+
+			got, err := tt.list()
+			if !errors.Is(err, ErrListingTruncated) {
+				t.Fatalf("error = %v, want %v", err, ErrListingTruncated)
+			}
+
+			if !slices.Equal(got, tt.wantNames) {
+				t.Errorf("entries = %v, want %v", got, tt.wantNames)
+			}
+
+			// the pages of the listing, besides the stat of the directory
+			if got := client.Count("ListObjectsV2"); got > 3 {
+				t.Errorf("ListObjectsV2 calls = %d, want at most 3", got)
+			}
+		})
+	}
+}
+
+func TestReadDirFileAndDirectory(t *testing.T) {
+	tests := []struct {
+		name    string
+		fillers int
+	}{
+		{name: "same page"},
+		// the object and the prefix are listed in different pages
+		{name: "across pages", fillers: 998},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+			for i := 0; i < tt.fillers; i++ {
+				client.Put("test", fmt.Sprintf("a%04d", i), nil)
+			}
+			client.Put("test", "data", []byte("file"))
+			client.Put("test", "data.txt", []byte("file"))
+			client.Put("test", "data/x", []byte("file"))
+
+			entries, err := fsys.ReadDir(".")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, entry := range entries[1+tt.fillers:] {
+				got = append(got, fmt.Sprintf("%s %v", entry.Name(), entry.IsDir()))
+			}
+
+			want := []string{"data true", "data.txt false"}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("ReadDir() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadDirSys(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+	client.Put("test", "prefix/dir/file", []byte("content"))
+	client.Put("test", "prefix/dir/sub/file", []byte("content"))
+
+	entries, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		obj, ok := info.Sys().(types.Object)
+		if entry.IsDir() {
+			if ok {
+				t.Errorf("%s Sys() = %T, want nil", entry.Name(), info.Sys())
+			}
+			continue
+		}
+
+		if !ok {
+			t.Fatalf("%s Sys() = %T, want types.Object", entry.Name(), info.Sys())
+		}
+		if aws.ToString(obj.Key) != "prefix/dir/file" || obj.StorageClass != types.ObjectStorageClassStandard || aws.ToString(obj.ETag) == "" {
+			t.Errorf("%s Sys() = %+v", entry.Name(), obj)
+		}
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+	for _, key := range []string{
+		"prefix/dir/.keep",
+		"prefix/dir/b.txt",
+		"prefix/dir/a.txt",
+		"prefix/dir/both",
+		"prefix/dir/both/c.txt",
+		"prefix/dir/sub/d.txt",
+		"prefix/dir/empty/.keep",
+	} {
+		client.Put("test", key, []byte(key))
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "dir", want: []string{"a.txt", "b.txt"}},
+		{name: "dir/sub", want: []string{"d.txt"}},
+		{name: "dir/empty", want: []string{}},
+		{name: "missing", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := fsys.ReadFiles(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				if entry.IsDir() {
+					t.Errorf("%s is a directory", entry.Name())
+				}
+				got = append(got, entry.Name())
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ReadFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := fsys.ReadFiles("dir/a.txt"); !errors.Is(err, ErrNotDirectory) {
+		t.Errorf("ReadFiles() error = %v, want %v", err, ErrNotDirectory)
+	}
+}
+
+func TestOpenLatest(t *testing.T) {
+	fsys, client := newTestFs(t)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	objects := []struct {
+		key     string
+		minutes int
+	}{
+		{key: "logs/a.log", minutes: 2},
+		{key: "logs/b.log", minutes: 5},
+		{key: "logs/c.log", minutes: 1},
+		{key: "logs/d.log", minutes: 5},
+		{key: "logs/sub/e.log", minutes: 10},
+		{key: "logs/.keep", minutes: 10},
+		{key: "other.log", minutes: 10},
+	}
+
+	for _, obj := range objects {
+		client.Now = func() time.Time { return start.Add(time.Duration(obj.minutes) * time.Minute) }
+		client.Put("test", obj.key, []byte(obj.key))
+	}
+
+	f, name, err := fsys.OpenLatest("logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// b.log and d.log are the newest files, d.log sorts last
+	if name != "logs/d.log" {
+		t.Errorf("OpenLatest() name = %q, want %q", name, "logs/d.log")
+	}
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "logs/d.log" {
+		t.Errorf("content = %q, want %q", got, "logs/d.log")
+	}
+
+	client.Put("test", "logs/empty/.keep", nil)
+
+	for _, dir := range []string{"logs/empty", "missing"} {
+		if _, _, err := fsys.OpenLatest(dir); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("OpenLatest(%q) error = %v, want %v", dir, err, fs.ErrNotExist)
+		}
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys, client := newTestFs(t, WithClock(func() time.Time { return now }))
+	client.Put("test", "dir/file", []byte("content"))
+
+	info, err := fsys.Stat("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.ModTime().Equal(now) {
+		t.Errorf("directory ModTime() = %v, want %v", info.ModTime(), now)
+	}
+}
+
+func TestWithMaxKeys(t *testing.T) {
+	fsys, client := newTestFs(t, WithMaxKeys(2))
+	for i := 0; i < 5; i++ {
+		client.Put("test", fmt.Sprintf("dir/file%d", i), nil)
+	}
+
+	entries, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 6 {
+		t.Errorf("entries = %d, want 6", len(entries))
+	}
+
+	if _, err := fsys.Stat("dir/file4"); err != nil {
+		t.Fatal(err)
+	}
+
+	var pages int
+	for _, call := range client.Calls() {
+		in, ok := call.Input.(*s3.ListObjectsV2Input)
+		if !ok {
+			continue
+		}
+		pages++
+		if got := aws.ToInt32(in.MaxKeys); got != 2 {
+			t.Errorf("ListObjectsV2(%s) MaxKeys = %d, want 2", aws.ToString(in.Prefix), got)
+		}
+	}
+
+	// the stat of dir, the 3 pages of dir and the stat of dir/file4
+	if pages != 5 {
+		t.Errorf("ListObjectsV2 calls = %d, want 5", pages)
+	}
+}
+
+func TestWithMaxKeysOutOfRange(t *testing.T) {
+	for _, n := range []int32{-1, 0, 1001, 5000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			fsys, client := newTestFs(t, WithMaxKeys(n))
+			client.Put("test", "dir/file", nil)
+
+			if _, err := fsys.ReadDir("dir"); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, call := range client.Calls() {
+				if in, ok := call.Input.(*s3.ListObjectsV2Input); ok && in.MaxKeys != nil {
+					t.Errorf("ListObjectsV2(%s) MaxKeys = %d, want the default", aws.ToString(in.Prefix), *in.MaxKeys)
+				}
+			}
+		})
+	}
+}
+
+func TestPartSizeLimits(t *testing.T) {
+	// https://docs.aws.amazon.com/AmazonS3/latest/userguide/qfacts.html
+	if MinPartSize != 5<<20 {
+		t.Errorf("MinPartSize = %d, want 5 MiB", MinPartSize)
+	}
+
+	if MaxPartSize != 5<<30 {
+		t.Errorf("MaxPartSize = %d, want 5 GiB", MaxPartSize)
+	}
+
+	if MaxParts != 10000 {
+		t.Errorf("MaxParts = %d, want 10000", MaxParts)
+	}
+}
+
+func TestEffectivePartSize(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want int64
+	}{
+		{
+			name: "default",
+			want: MinPartSize,
+		},
+		{
+			name: "part size",
+			opts: []Option{WithPartSize(8 << 20)},
+			want: 8 << 20,
+		},
+		{
+			name: "part size too small",
+			opts: []Option{WithPartSize(MinPartSize - 1)},
+			want: MinPartSize,
+		},
+		{
+			name: "minimum part size",
+			opts: []Option{WithPartSize(MinPartSize)},
+			want: MinPartSize,
+		},
+		{
+			name: "upload part size",
+			opts: []Option{WithPartSize(8 << 20), WithUploadPartSize(16 << 20)},
+			want: 16 << 20,
+		},
+		{
+			name: "upload part size too small",
+			opts: []Option{WithPartSize(8 << 20), WithUploadPartSize(1)},
+			want: 8 << 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, _ := newTestFs(t, tt.opts...)
+
+			if got := fsys.EffectivePartSize(); got != tt.want {
+				t.Errorf("EffectivePartSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadDirFlat(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+	for _, key := range []string{
+		"prefix/dir/a",
+		"prefix/dir/.keep",
+		"prefix/dir/sub/b",
+		"prefix/dir/sub/deep/er/c",
+		"prefix/dir/sub/.keep",
+		"prefix/other/d",
+	} {
+		client.Put("test", key, []byte(key))
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "dir", want: []string{"a", "sub/b", "sub/deep/er/c"}},
+		{name: ".", want: []string{"dir/a", "dir/sub/b", "dir/sub/deep/er/c", "other/d"}},
+		{name: "missing", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := fsys.ReadDirFlat(context.Background(), tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				if entry.IsDir() {
+					t.Errorf("%s is a directory", entry.Name())
+				}
+				got = append(got, entry.Name())
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ReadDirFlat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := fsys.ReadDirFlat(context.Background(), "dir/a"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("ReadDirFlat() error = %v, want %v", err, fs.ErrInvalid)
+	}
+}
+
+func TestListBySuffix(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+	for _, key := range []string{
+		"prefix/data/b.parquet",
+		"prefix/data/a.csv",
+		"prefix/data/.keep",
+		"prefix/data/2024/c.parquet",
+		"prefix/data/2024/c.parquet.tmp",
+		"prefix/data/2023/a.parquet",
+		"prefix/other/d.parquet",
+	} {
+		client.Put("test", key, []byte(key))
+	}
+
+	tests := []struct {
+		name   string
+		suffix string
+		want   []string
+	}{
+		{name: "data", suffix: ".parquet", want: []string{"data/2023/a.parquet", "data/2024/c.parquet", "data/b.parquet"}},
+		{name: ".", suffix: ".parquet", want: []string{"data/2023/a.parquet", "data/2024/c.parquet", "data/b.parquet", "other/d.parquet"}},
+		{name: "data", suffix: ".json", want: []string{}},
+		{name: "missing", suffix: ".parquet", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+tt.suffix, func(t *testing.T) {
+			got, err := fsys.ListBySuffix(tt.name, tt.suffix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ListBySuffix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateNestedDirectory(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		// stale caches the info of a file a/b before the directory replaces it
+		stale bool
+	}{
+		{name: "list based"},
+		{name: "head based", opts: []Option{WithStatStrategy(HeadBased)}},
+		{name: "stale stat cache", opts: []Option{WithStatCache(time.Minute)}, stale: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t, tt.opts...)
+
+			if tt.stale {
+				client.Put("test", "a/b", []byte("file"))
+				if _, err := fsys.Stat("a/b"); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := client.DeleteObject(context.Background(), &s3.DeleteObjectInput{Bucket: aws.String("test"), Key: aws.String("a/b")}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			client.Put("test", "a/b/c.txt", []byte("content"))
+
+			_, err := fsys.Create("a/b")
+			if !errors.Is(err, ErrIsDirectory) || !errors.Is(err, fs.ErrExist) {
+				t.Errorf("Create() error = %v, want %v", err, ErrIsDirectory)
+			}
+
+			if _, found := client.Object("test", "a/b"); found {
+				t.Error("object a/b was created")
+			}
+		})
+	}
+}
+
+func TestCreateRoot(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithPrefix("prefix")}} {
+		fsys, client := newTestFs(t, opts...)
+
+		for _, name := range []string{".", "", "/"} {
+			if _, err := fsys.Create(name); !errors.Is(err, ErrIsDirectory) || !errors.Is(err, fs.ErrExist) {
+				t.Errorf("Create(%q) error = %v, want %v", name, err, ErrIsDirectory)
+			}
+		}
+
+		if got := client.Count("PutObject"); got != 0 {
+			t.Errorf("PutObject calls = %d, want 0", got)
+		}
+	}
+}
+
+func TestCreateDirPlaceholderKey(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+	client.Put("test", "prefix/implicit/file", nil)
+
+	dir, err := fsys.CreateDir("a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, ok := dir.PlaceholderKey()
+	if want := fsys.withPrefix("a/b", directoryFile); !ok || key != want {
+		t.Errorf("PlaceholderKey() = (%q, %v), want (%q, true)", key, ok, want)
+	}
+
+	if _, found := client.Object("test", key); !found {
+		t.Errorf("object %q not found", key)
+	}
+
+	f, err := fsys.Open("implicit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key, ok := f.(*Directory).PlaceholderKey(); ok {
+		t.Errorf("PlaceholderKey() = (%q, %v), want (\"\", false)", key, ok)
+	}
+}
+
+func TestDirectoryReadDir(t *testing.T) {
+	fsys, client := newTestFs(t)
+	for i := 0; i < 2500; i++ {
+		client.Put("test", fmt.Sprintf("dir/file%04d", i), nil)
+	}
+	client.Put("test", "dir/sub/file", nil)
+
+	f, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		t.Fatalf("Open() = %T, want fs.ReadDirFile", f)
+	}
+
+	var names []string
+	for {
+		entries, err := dir.ReadDir(1)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != 1 {
+			t.Fatalf("ReadDir(1) = %d entries, want 1", len(entries))
+		}
+		names = append(names, entries[0].Name())
+	}
+
+	if len(names) != 2501 {
+		t.Fatalf("entries = %d, want 2501", len(names))
+	}
+
+	if names[0] != "file0000" || names[2499] != "file2499" || names[2500] != "sub" {
+		t.Errorf("entries = [%s ... %s %s]", names[0], names[2499], names[2500])
+	}
+
+	if got := client.Count("ListObjectsV2"); got != 4 {
+		t.Errorf("ListObjectsV2 calls = %d, want 4", got)
+	}
+
+	if entries, err := dir.ReadDir(-1); err != nil || len(entries) != 0 {
+		t.Errorf("ReadDir(-1) = (%d, %v), want (0, nil)", len(entries), err)
+	}
+}
+
+func TestDirectoryReaddirnames(t *testing.T) {
+	fsys, client := newTestFs(t)
+	for i := 0; i < 2500; i++ {
+		client.Put("test", fmt.Sprintf("dir/file%04d", i), nil)
+	}
+	client.Put("test", "dir/sub/file", nil)
+
+	f, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := f.(*Directory)
+
+	var names []string
+	for {
+		batch, err := dir.Readdirnames(100)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(batch) == 0 || len(batch) > 100 {
+			t.Fatalf("Readdirnames(100) = %d names", len(batch))
+		}
+		names = append(names, batch...)
+	}
+
+	if len(names) != 2501 {
+		t.Fatalf("names = %d, want 2501", len(names))
+	}
+
+	if names[0] != "file0000" || names[2499] != "file2499" || names[2500] != "sub" {
+		t.Errorf("names = [%s ... %s %s]", names[0], names[2499], names[2500])
+	}
+
+	if names, err := dir.Readdirnames(0); err != nil || len(names) != 0 {
+		t.Errorf("Readdirnames(0) = (%d, %v), want (0, nil)", len(names), err)
+	}
+}
+
+func TestDirectoryReaddir(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "dir/a", []byte("content"))
+	client.Put("test", "dir/b/file", nil)
+
+	f, err := fsys.Open("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := f.(*Directory)
+
+	infos, err := dir.Readdir(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 1 || infos[0].Name() != "a" || infos[0].Size() != 7 || infos[0].IsDir() {
+		t.Fatalf("Readdir(1) = %v", infos)
+	}
+
+	infos, err = dir.Readdir(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 1 || infos[0].Name() != "b" || !infos[0].IsDir() {
+		t.Fatalf("Readdir(-1) = %v", infos)
+	}
+
+	if _, err := dir.Readdir(1); !errors.Is(err, io.EOF) {
+		t.Errorf("Readdir(1) error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestDirectoryReadDirPrefix(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"), WithMaxKeys(2))
+	for _, key := range []string{"prefix/a", "prefix/b", "prefix/c/d", "prefix/e", "other/f", "g"} {
+		client.Put("test", key, nil)
+	}
+
+	f, err := fsys.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		t.Fatalf("Open() = %T, want fs.ReadDirFile", f)
+	}
+
+	var names []string
+	for {
+		entries, err := dir.ReadDir(1)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+	}
+
+	if want := []string{"a", "b", "c", "e"}; !slices.Equal(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+}
+
+func TestRepairDirectories(t *testing.T) {
+	fsys, client := newTestFs(t)
+	for _, key := range []string{"data/a/b/c.txt", "data/a/d.txt", "data/e/.keep", "data/e/f.txt", "data/g.txt", "other/h.txt"} {
+		client.Put("test", key, nil)
+	}
+
+	created, err := fsys.RepairDirectories("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created != 3 {
+		t.Errorf("RepairDirectories() = %d, want 3", created)
+	}
+
+	wantKeys := []string{"data/.keep", "data/a/.keep", "data/a/b/.keep", "data/a/b/c.txt", "data/a/d.txt", "data/e/.keep", "data/e/f.txt", "data/g.txt", "other/h.txt"}
+	if got := client.Keys("test"); !slices.Equal(got, wantKeys) {
+		t.Errorf("keys = %v, want %v", got, wantKeys)
+	}
+
+	if created, err := fsys.RepairDirectories("data"); err != nil || created != 0 {
+		t.Errorf("RepairDirectories() again = (%d, %v), want (0, nil)", created, err)
+	}
+
+	for _, name := range []string{"data/a/b/c.txt", "data/a/d.txt"} {
+		if err := fsys.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for dir, want := range map[string][]string{"data": {".", "a", "e", "g.txt"}, "data/a": {".", "b"}} {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+
+		if !slices.Equal(names, want) {
+			t.Errorf("ReadDir(%s) = %v, want %v", dir, names, want)
+		}
+	}
+}
+
+func TestMkdirAll(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "a/file", nil)
+
+	if err := fsys.MkdirAll(context.Background(), "a/b/c"); err != nil {
+		t.Fatal(err)
+	}
+
+	wantKeys := []string{"a/b/.keep", "a/b/c/.keep", "a/file"}
+	if got := client.Keys("test"); fmt.Sprint(got) != fmt.Sprint(wantKeys) {
+		t.Errorf("keys = %v, want %v", got, wantKeys)
+	}
+
+	for dir, want := range map[string]string{"a": "b", "a/b": "c"} {
+		entries, err := fsys.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := false
+		for _, entry := range entries {
+			found = found || (entry.Name() == want && entry.IsDir())
+		}
+		if !found {
+			t.Errorf("ReadDir(%s) has no directory %s", dir, want)
+		}
+	}
+
+	// existing directories are not an error
+	client.Reset()
+	if err := fsys.MkdirAll(context.Background(), "a/b/c"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.Count("PutObject"); got != 0 {
+		t.Errorf("PutObject calls = %d, want 0", got)
+	}
+
+	if err := fsys.MkdirAll(context.Background(), "a/file/d"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("MkdirAll() error = %v, want %v", err, fs.ErrExist)
+	}
+}
+
+func TestOpenTrailingSlash(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "report.pdf", []byte("content"))
+
+	if _, err := fsys.Open("report.pdf/"); !errors.Is(err, ErrNotDirectory) || !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Open(report.pdf/) error = %v, want %v", err, ErrNotDirectory)
+	}
+
+	f, err := fsys.Open("report.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatRoot(t *testing.T) {
+	fsys, _ := newTestFs(t)
+
+	info, err := fsys.Stat(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.IsDir() || info.Name() != currentDirName {
+		t.Errorf("Stat(.) = %s IsDir=%v, want . IsDir=true", info.Name(), info.IsDir())
+	}
+
+	missing := New(s3fstest.New(), "missing")
+
+	if _, err := missing.Stat("."); !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("Stat(.) error = %v, want %v", err, ErrBucketNotFound)
+	}
+}
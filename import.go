@@ -0,0 +1,115 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// CopyFromOption configures CopyFrom.
+type CopyFromOption func(*copyFromOptions)
+
+type copyFromOptions struct {
+	continueOnError bool
+}
+
+// WithContinueOnError keeps copying the remaining files when one fails,
+// CopyFrom then returns the errors joined.
+func WithContinueOnError() CopyFromOption {
+	return func(o *copyFromOptions) {
+		o.continueOnError = true
+	}
+}
+
+// CopyFrom uploads every regular file of src to the same name under the Fs,
+// streaming each one, and creates the directory file of the empty directories.
+// It stops at the first error unless WithContinueOnError is set.
+func (f *Fs) CopyFrom(ctx context.Context, src fs.FS, opts ...CopyFromOption) error {
+	var o copyFromOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		errs []error
+		// emptyDirs are the directories without entries so far
+		emptyDirs = make(map[string]bool)
+	)
+
+	walkErr := fs.WalkDir(src, ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil {
+			err = ctx.Err()
+		}
+
+		if err == nil && name != "." {
+			delete(emptyDirs, path.Dir(name))
+
+			switch {
+			case d.IsDir():
+				emptyDirs[name] = true
+			case d.Type().IsRegular():
+				err = f.copyFromFile(ctx, src, name)
+			}
+		}
+
+		if err != nil {
+			err = &fs.PathError{Op: "copyfrom", Path: name, Err: err}
+			if !o.continueOnError || ctx.Err() != nil {
+				return err
+			}
+			errs = append(errs, err)
+		}
+
+		return nil
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	for name := range emptyDirs {
+		if err := f.putDirectoryFile(ctx, name); err != nil {
+			err = &fs.PathError{Op: "copyfrom", Path: name, Err: mapError(err)}
+			if !o.continueOnError {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// copyFromFile uploads the named file of src.
+func (f *Fs) copyFromFile(ctx context.Context, src fs.FS, name string) error {
+	if err := f.checkKey("copyfrom", name); err != nil {
+		return err
+	}
+
+	r, err := src.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	f.invalidate(f.cleanPath(name))
+
+	w := &File{
+		ctx:  ctx,
+		fs:   f,
+		info: regularFileInfo(f.cleanPath(name), 0, f.now()),
+	}
+	if err := w.openWriter(ctx); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		// cancels the upload, so the partial content is not stored
+		w.writerCancelFn()
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
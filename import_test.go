@@ -0,0 +1,89 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// failingFS fails to open the name fail.
+type failingFS struct {
+	fstest.MapFS
+	fail string
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if name == f.fail {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.MapFS.Open(name)
+}
+
+func TestCopyFrom(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+
+	src := fstest.MapFS{
+		"a.txt":         {Data: []byte("a")},
+		"dir/b.txt":     {Data: []byte("b")},
+		"dir/sub/c.txt": {Data: []byte("c")},
+		"empty":         {Mode: fs.ModeDir},
+	}
+
+	if err := fsys.CopyFrom(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"prefix/a.txt":         "a",
+		"prefix/dir/b.txt":     "b",
+		"prefix/dir/sub/c.txt": "c",
+		"prefix/empty/.keep":   "",
+	}
+
+	if keys := client.Keys("test"); len(keys) != len(want) {
+		t.Errorf("keys = %v, want %d keys", keys, len(want))
+	}
+
+	for key, content := range want {
+		obj, found := client.Object("test", key)
+		if !found || string(obj.Data) != content {
+			t.Errorf("object %s = (%q, %v), want (%q, true)", key, obj.Data, found, content)
+		}
+	}
+}
+
+func TestCopyFromError(t *testing.T) {
+	src := failingFS{
+		MapFS: fstest.MapFS{
+			"a.txt": {Data: []byte("a")},
+			"b.txt": {Data: []byte("b")},
+			"c.txt": {Data: []byte("c")},
+		},
+		fail: "b.txt",
+	}
+
+	tests := []struct {
+		name     string
+		opts     []CopyFromOption
+		wantKeys int
+	}{
+		{name: "abort", wantKeys: 1},
+		{name: "continue", opts: []CopyFromOption{WithContinueOnError()}, wantKeys: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+
+			if err := fsys.CopyFrom(context.Background(), src, tt.opts...); !errors.Is(err, fs.ErrPermission) {
+				t.Errorf("CopyFrom() error = %v, want %v", err, fs.ErrPermission)
+			}
+
+			if keys := client.Keys("test"); len(keys) != tt.wantKeys {
+				t.Errorf("keys = %v, want %d keys", keys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package s3fs
+
+import (
+	"io/fs"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// listCacheSize is the maximum number of directories kept by the list cache.
+const listCacheSize = 256
+
+// listCache is a concurrency safe cache of ReadDir results keyed by cleaned directory name.
+// A nil *listCache is a disabled cache.
+type listCache struct {
+	now func() time.Time
+	// dir returns the parent directory of a name.
+	dir     func(string) string
+	entries map[string]listCacheEntry
+	ttl     time.Duration
+	size    int
+	mu      sync.Mutex
+}
+
+type listCacheEntry struct {
+	expires time.Time
+	entries []fs.DirEntry
+}
+
+func newListCache(ttl time.Duration, size int) *listCache {
+	return &listCache{
+		now:     time.Now,
+		dir:     parentDir,
+		entries: make(map[string]listCacheEntry),
+		ttl:     ttl,
+		size:    size,
+	}
+}
+
+// get returns a copy of the entries of the directory, so callers may modify it.
+func (c *listCache) get(dirName string) ([]fs.DirEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[dirName]
+	if !found {
+		return nil, false
+	}
+
+	if c.now().After(entry.expires) {
+		delete(c.entries, dirName)
+		return nil, false
+	}
+
+	return slices.Clone(entry.entries), true
+}
+
+func (c *listCache) set(dirName string, entries []fs.DirEntry) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if _, found := c.entries[dirName]; !found && len(c.entries) >= c.size {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+
+		// every listing is recent, starts over
+		if len(c.entries) >= c.size {
+			clear(c.entries)
+		}
+	}
+
+	c.entries[dirName] = listCacheEntry{entries: slices.Clone(entries), expires: now.Add(c.ttl)}
+}
+
+// invalidate removes the listings of the parent directories of the cleaned names, up to the root,
+// and the listings of the names themselves and their subdirectories.
+func (c *listCache) invalidate(names ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		for key := name; ; key = c.dir(key) {
+			if key == currentDirName {
+				key = ""
+			}
+
+			delete(c.entries, key)
+
+			if key == "" {
+				break
+			}
+		}
+
+		if name == "" || name == currentDirName {
+			continue
+		}
+
+		for key := range c.entries {
+			if strings.HasPrefix(key, name+pathSeparator) {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
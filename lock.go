@@ -0,0 +1,61 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// LockStatus is the object lock state of an object.
+// Objects without a lock have the zero value.
+type LockStatus struct {
+	// RetainUntil is the end of the retention period.
+	RetainUntil time.Time
+	// Mode is the retention mode, GOVERNANCE or COMPLIANCE.
+	Mode string
+	// LegalHold reports whether a legal hold is in effect.
+	LegalHold bool
+}
+
+// LockStatus returns the retention and legal hold of the named file.
+// They are read from the headers of a single HeadObject request,
+// which S3 only includes with the s3:GetObjectRetention and s3:GetObjectLegalHold permissions.
+func (f *Fs) LockStatus(name string) (LockStatus, error) {
+	return f.LockStatusWithContext(context.Background(), name)
+}
+
+// LockStatusWithContext returns the retention and legal hold of the named file.
+// They are read from the headers of a single HeadObject request,
+// which S3 only includes with the s3:GetObjectRetention and s3:GetObjectLegalHold permissions.
+func (f *Fs) LockStatusWithContext(ctx context.Context, name string) (LockStatus, error) {
+	if f.cleanPath(name) == "" {
+		return LockStatus{}, &fs.PathError{Op: "lockstatus", Path: name, Err: ErrIsDirectory}
+	}
+
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return LockStatus{}, &fs.PathError{Op: "lockstatus", Path: name, Err: fs.ErrNotExist}
+		}
+		return LockStatus{}, mapError(err)
+	}
+
+	return LockStatus{
+		Mode:        string(res.ObjectLockMode),
+		RetainUntil: aws.ToTime(res.ObjectLockRetainUntilDate),
+		LegalHold:   res.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn,
+	}, nil
+}
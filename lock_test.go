@@ -0,0 +1,69 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestLockStatus(t *testing.T) {
+	fsys, client := newTestFs(t)
+	retainUntil := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	_, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:                    aws.String("test"),
+		Key:                       aws.String("locked"),
+		Body:                      strings.NewReader("content"),
+		ObjectLockMode:            types.ObjectLockModeCompliance,
+		ObjectLockRetainUntilDate: aws.Time(retainUntil),
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Put("test", "unlocked", []byte("content"))
+
+	tests := []struct {
+		name string
+		want LockStatus
+	}{
+		{
+			name: "locked",
+			want: LockStatus{Mode: "COMPLIANCE", RetainUntil: retainUntil, LegalHold: true},
+		},
+		{
+			name: "unlocked",
+			want: LockStatus{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.Reset()
+
+			got, err := fsys.LockStatus(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("LockStatus() = %+v, want %+v", got, tt.want)
+			}
+
+			if got := len(client.Calls()); got != 1 {
+				t.Errorf("calls = %d, want 1", got)
+			}
+		})
+	}
+
+	if _, err := fsys.LockStatus("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LockStatus() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -0,0 +1,113 @@
+package s3fs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// LogFunc receives each S3 operation, named as in the S3 API (e.g. GetObject),
+// with the key or listing prefix, its duration and error.
+type LogFunc func(ctx context.Context, op string, key string, d time.Duration, err error)
+
+// WithLogger sets the function called after each S3 call, including each attempt of WithRetry.
+// Implementations must be safe for concurrent use.
+func WithLogger(fn LogFunc) Option {
+	return func(f *Fs) {
+		f.logger = fn
+	}
+}
+
+// logClient reports every S3 call to a LogFunc.
+type logClient struct {
+	client s3ApiClient
+	log    LogFunc
+}
+
+func (c *logClient) unwrap() s3ApiClient { return c.client }
+
+func (c *logClient) observe(ctx context.Context, op string, key *string, start time.Time, err error) {
+	c.log(ctx, op, aws.ToString(key), time.Since(start), err)
+}
+
+func (c *logClient) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (out *s3.HeadBucketOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "HeadBucket", nil, start, err) }(time.Now())
+	return c.client.HeadBucket(ctx, params, optFns...)
+}
+
+func (c *logClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (out *s3.HeadObjectOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "HeadObject", params.Key, start, err) }(time.Now())
+	return c.client.HeadObject(ctx, params, optFns...)
+}
+
+func (c *logClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (out *s3.CopyObjectOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "CopyObject", params.Key, start, err) }(time.Now())
+	return c.client.CopyObject(ctx, params, optFns...)
+}
+
+func (c *logClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (out *s3.PutObjectOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "PutObject", params.Key, start, err) }(time.Now())
+	return c.client.PutObject(ctx, params, optFns...)
+}
+
+func (c *logClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (out *s3.GetObjectOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "GetObject", params.Key, start, err) }(time.Now())
+	return c.client.GetObject(ctx, params, optFns...)
+}
+
+func (c *logClient) GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (out *s3.GetObjectAttributesOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "GetObjectAttributes", params.Key, start, err) }(time.Now())
+	return c.client.GetObjectAttributes(ctx, params, optFns...)
+}
+
+func (c *logClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (out *s3.DeleteObjectOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "DeleteObject", params.Key, start, err) }(time.Now())
+	return c.client.DeleteObject(ctx, params, optFns...)
+}
+
+func (c *logClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (out *s3.DeleteObjectsOutput, err error) {
+	var key *string
+	if params.Delete != nil && len(params.Delete.Objects) > 0 {
+		// the first key of the batch
+		key = params.Delete.Objects[0].Key
+	}
+	defer func(start time.Time) { c.observe(ctx, "DeleteObjects", key, start, err) }(time.Now())
+	return c.client.DeleteObjects(ctx, params, optFns...)
+}
+
+func (c *logClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (out *s3.ListObjectsV2Output, err error) {
+	defer func(start time.Time) { c.observe(ctx, "ListObjectsV2", params.Prefix, start, err) }(time.Now())
+	return c.client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func (c *logClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (out *s3.ListObjectVersionsOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "ListObjectVersions", params.Prefix, start, err) }(time.Now())
+	return c.client.ListObjectVersions(ctx, params, optFns...)
+}
+
+func (c *logClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (out *s3.UploadPartOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "UploadPart", params.Key, start, err) }(time.Now())
+	return c.client.UploadPart(ctx, params, optFns...)
+}
+
+func (c *logClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (out *s3.UploadPartCopyOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "UploadPartCopy", params.Key, start, err) }(time.Now())
+	return c.client.UploadPartCopy(ctx, params, optFns...)
+}
+
+func (c *logClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (out *s3.CreateMultipartUploadOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "CreateMultipartUpload", params.Key, start, err) }(time.Now())
+	return c.client.CreateMultipartUpload(ctx, params, optFns...)
+}
+
+func (c *logClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (out *s3.CompleteMultipartUploadOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "CompleteMultipartUpload", params.Key, start, err) }(time.Now())
+	return c.client.CompleteMultipartUpload(ctx, params, optFns...)
+}
+
+func (c *logClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (out *s3.AbortMultipartUploadOutput, err error) {
+	defer func(start time.Time) { c.observe(ctx, "AbortMultipartUpload", params.Key, start, err) }(time.Now())
+	return c.client.AbortMultipartUpload(ctx, params, optFns...)
+}
@@ -0,0 +1,75 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		logs []string
+	)
+
+	fsys, _ := newTestFs(t, WithLogger(func(_ context.Context, op, key string, _ time.Duration, _ error) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, op+" "+key)
+	}))
+
+	w, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fsys.Remove("file"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"ListObjectsV2 file/",
+		"PutObject file",
+		"HeadObject file",
+		"ListObjectsV2 file",
+		"GetObject file",
+		"ListObjectsV2 file",
+		"DeleteObject file",
+	}
+
+	if strings.Join(logs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("logs =\n%s\nwant\n%s", strings.Join(logs, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoggerBaseClient(t *testing.T) {
+	fsys, client := newTestFs(t, WithLogger(func(context.Context, string, string, time.Duration, error) {}))
+
+	if got := baseClient(fsys.client); got != client {
+		t.Errorf("baseClient() = %T, want %T", got, client)
+	}
+}
@@ -0,0 +1,156 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ObjectMetadata holds the HTTP headers and user metadata of an object.
+type ObjectMetadata struct {
+	Metadata        map[string]string
+	ContentType     string
+	ContentLanguage string
+	ContentEncoding string
+	ETag            string
+}
+
+// WithContentLanguage sets the Content-Language of the written object.
+func WithContentLanguage(lang string) WriteOption {
+	return func(o *writeOptions) {
+		o.contentLanguage = aws.String(lang)
+	}
+}
+
+// WithContentTypeMap sets the Content-Type of written files from their extension,
+// such as ".geojson" to "application/geo+json", looked up in types
+// before mime.TypeByExtension. Files of unknown extensions have no Content-Type.
+func WithContentTypeMap(types map[string]string) Option {
+	return func(f *Fs) {
+		f.contentTypes = make(map[string]string, len(types))
+		for ext, contentType := range types {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			f.contentTypes[strings.ToLower(ext)] = contentType
+		}
+	}
+}
+
+// contentType returns the Content-Type of the named file, nil without WithContentTypeMap.
+func (f *Fs) contentType(name string) *string {
+	if f.contentTypes == nil {
+		return nil
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return nil
+	}
+
+	if contentType, found := f.contentTypes[ext]; found {
+		return aws.String(contentType)
+	}
+
+	return nonEmpty(mime.TypeByExtension(ext))
+}
+
+// Headers returns the metadata of the named file.
+func (f *Fs) Headers(name string) (ObjectMetadata, error) {
+	return f.HeadersWithContext(context.Background(), name)
+}
+
+// HeadersWithContext returns the metadata of the named file.
+func (f *Fs) HeadersWithContext(ctx context.Context, name string) (ObjectMetadata, error) {
+	res, err := f.headFile(ctx, "headers", name)
+	if err != nil {
+		return ObjectMetadata{}, err
+	}
+
+	return ObjectMetadata{
+		Metadata:        res.Metadata,
+		ContentType:     aws.ToString(res.ContentType),
+		ContentLanguage: aws.ToString(res.ContentLanguage),
+		ContentEncoding: aws.ToString(res.ContentEncoding),
+		ETag:            aws.ToString(res.ETag),
+	}, nil
+}
+
+// headFile returns the HeadObject of the named file,
+// with an error matching ErrIsDirectory for directories.
+func (f *Fs) headFile(ctx context.Context, op, name string) (*s3.HeadObjectOutput, error) {
+	if f.cleanPath(name) == "" {
+		return nil, &fs.PathError{Op: op, Path: name, Err: ErrIsDirectory}
+	}
+
+	headCtx := ctx
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		headCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(headCtx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name)),
+	})
+	if err != nil {
+		if !isNotFound(err) {
+			return nil, mapError(err)
+		}
+
+		// directories have no object, only keys under their prefix
+		if info, err := f.StatWithContext(ctx, name); err == nil && info.IsDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: ErrIsDirectory}
+		}
+
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return res, nil
+}
+
+// copyOntoItself copies the named file described by head onto itself,
+// replacing its user metadata with meta, and its Content-Type with contentType unless empty.
+// The other headers, storage class, encryption settings and tags of the object are kept.
+func (f *Fs) copyOntoItself(ctx context.Context, name string, head *s3.HeadObjectOutput, meta map[string]string, contentType string) error {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	if contentType == "" {
+		contentType = aws.ToString(head.ContentType)
+	}
+
+	_, err := f.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:                  aws.String(f.bucket),
+		Key:                     aws.String(f.withPrefix(name)),
+		CopySource:              aws.String(f.copySource(name)),
+		MetadataDirective:       types.MetadataDirectiveReplace,
+		TaggingDirective:        types.TaggingDirectiveCopy,
+		Metadata:                meta,
+		ContentType:             nonEmpty(contentType),
+		CacheControl:            head.CacheControl,
+		ContentDisposition:      head.ContentDisposition,
+		ContentEncoding:         head.ContentEncoding,
+		ContentLanguage:         head.ContentLanguage,
+		Expires:                 head.Expires,
+		WebsiteRedirectLocation: head.WebsiteRedirectLocation,
+		StorageClass:            head.StorageClass,
+		ServerSideEncryption:    head.ServerSideEncryption,
+		SSEKMSKeyId:             head.SSEKMSKeyId,
+		BucketKeyEnabled:        head.BucketKeyEnabled,
+		ACL:                     f.acl,
+	})
+	f.invalidate(f.cleanPath(name))
+
+	return mapError(err)
+}
@@ -0,0 +1,125 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestContentLanguage(t *testing.T) {
+	fsys, _ := newTestFs(t)
+
+	f, err := fsys.Create("file", WithContentLanguage("pt-PT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("olá")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertContentLanguage(t, fsys, "file", "pt-PT")
+
+	if err := fsys.Rename("file", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertContentLanguage(t, fsys, "renamed", "pt-PT")
+
+	if err := fsys.UpdateMetadata(context.Background(), "renamed", map[string]string{"k": "v"}, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertContentLanguage(t, fsys, "renamed", "pt-PT")
+}
+
+func assertContentLanguage(t *testing.T, fsys *Fs, name, want string) {
+	t.Helper()
+
+	headers, err := fsys.Headers(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if headers.ContentLanguage != want {
+		t.Errorf("%s ContentLanguage = %q, want %q", name, headers.ContentLanguage, want)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "dir/file", []byte("content"))
+
+	headers, err := fsys.Headers("dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, _ := client.Object("test", "dir/file")
+	if headers.ETag != obj.ETag {
+		t.Errorf("ETag = %q, want %q", headers.ETag, obj.ETag)
+	}
+
+	for _, name := range []string{"dir", "."} {
+		if _, err := fsys.Headers(name); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("Headers(%q) error = %v, want %v", name, err, fs.ErrInvalid)
+		}
+	}
+
+	if _, err := fsys.Headers("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Headers() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestContentTypeMap(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		opts []Option
+	}{
+		{name: "map.geojson", want: "application/geo+json", opts: []Option{WithContentTypeMap(map[string]string{".geojson": "application/geo+json"})}},
+		{name: "MAP.GEOJSON", want: "application/geo+json", opts: []Option{WithContentTypeMap(map[string]string{"geojson": "application/geo+json"})}},
+		{name: "data.json", want: "application/json", opts: []Option{WithContentTypeMap(map[string]string{".geojson": "application/geo+json"})}},
+		{name: "data.unknown-ext", opts: []Option{WithContentTypeMap(map[string]string{})}},
+		{name: "map.geojson"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, _ := newTestFs(t, tt.opts...)
+
+			f, err := fsys.Create(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Write([]byte("{}")); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := fsys.Touch(context.Background(), "touched/"+tt.name); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range []string{tt.name, "touched/" + tt.name} {
+				headers, err := fsys.Headers(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if headers.ContentType != tt.want {
+					t.Errorf("%s ContentType = %q, want %q", name, headers.ContentType, tt.want)
+				}
+			}
+		})
+	}
+}
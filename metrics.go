@@ -0,0 +1,178 @@
+package s3fs
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// BytesRead is the AddBytes operation for downloaded bytes.
+	BytesRead = "read"
+	// BytesWritten is the AddBytes operation for uploaded bytes.
+	BytesWritten = "write"
+)
+
+// Recorder receives the metrics of the Fs.
+// Implementations must be safe for concurrent use.
+type Recorder interface {
+	// ObserveOp is called after each S3 operation, named as in the S3 API (e.g. GetObject).
+	ObserveOp(op string, dur time.Duration, err error)
+	// AddBytes is called with BytesRead or BytesWritten for each chunk transferred.
+	AddBytes(op string, n int64)
+}
+
+// WithMetrics sets the Recorder receiving the operations and bytes transferred.
+func WithMetrics(r Recorder) Option {
+	return func(f *Fs) {
+		f.recorder = r
+	}
+}
+
+// Metrics is a counter based alternative to Recorder, fitting Prometheus style collectors.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// IncOp is called after each S3 operation, named as in the S3 API (e.g. GetObject).
+	IncOp(op string)
+	// ObserveBytes is called with BytesRead or BytesWritten for each chunk transferred.
+	ObserveBytes(op string, n int64)
+	// ObserveLatency is called with the duration of each S3 operation.
+	ObserveLatency(op string, d time.Duration)
+}
+
+// NopMetrics is a Metrics discarding everything,
+// to embed in implementations interested in part of the methods.
+type NopMetrics struct{}
+
+func (NopMetrics) IncOp(string)                         {}
+func (NopMetrics) ObserveBytes(string, int64)           {}
+func (NopMetrics) ObserveLatency(string, time.Duration) {}
+
+// MetricsRecorder adapts m to a Recorder, to be given to WithMetrics.
+func MetricsRecorder(m Metrics) Recorder {
+	return metricsRecorder{metrics: m}
+}
+
+type metricsRecorder struct {
+	metrics Metrics
+}
+
+func (r metricsRecorder) ObserveOp(op string, dur time.Duration, _ error) {
+	r.metrics.IncOp(op)
+	r.metrics.ObserveLatency(op, dur)
+}
+
+func (r metricsRecorder) AddBytes(op string, n int64) {
+	r.metrics.ObserveBytes(op, n)
+}
+
+// addBytes reports n bytes transferred, when a Recorder is set.
+func (f *Fs) addBytes(op string, n int) {
+	if f.recorder != nil && n > 0 {
+		f.recorder.AddBytes(op, int64(n))
+	}
+}
+
+// unwrapper is implemented by the clients wrapping the one given to New.
+type unwrapper interface {
+	unwrap() s3ApiClient
+}
+
+// baseClient returns the client given to New.
+func baseClient(c s3ApiClient) s3ApiClient {
+	for {
+		u, ok := c.(unwrapper)
+		if !ok {
+			return c
+		}
+		c = u.unwrap()
+	}
+}
+
+// metricsClient reports every S3 operation to a Recorder.
+type metricsClient struct {
+	client   s3ApiClient
+	recorder Recorder
+}
+
+func (c *metricsClient) unwrap() s3ApiClient { return c.client }
+
+func (c *metricsClient) observe(op string, start time.Time, err error) {
+	c.recorder.ObserveOp(op, time.Since(start), err)
+}
+
+func (c *metricsClient) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (out *s3.HeadBucketOutput, err error) {
+	defer func(start time.Time) { c.observe("HeadBucket", start, err) }(time.Now())
+	return c.client.HeadBucket(ctx, params, optFns...)
+}
+
+func (c *metricsClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (out *s3.HeadObjectOutput, err error) {
+	defer func(start time.Time) { c.observe("HeadObject", start, err) }(time.Now())
+	return c.client.HeadObject(ctx, params, optFns...)
+}
+
+func (c *metricsClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (out *s3.CopyObjectOutput, err error) {
+	defer func(start time.Time) { c.observe("CopyObject", start, err) }(time.Now())
+	return c.client.CopyObject(ctx, params, optFns...)
+}
+
+func (c *metricsClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (out *s3.PutObjectOutput, err error) {
+	defer func(start time.Time) { c.observe("PutObject", start, err) }(time.Now())
+	return c.client.PutObject(ctx, params, optFns...)
+}
+
+func (c *metricsClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (out *s3.GetObjectOutput, err error) {
+	defer func(start time.Time) { c.observe("GetObject", start, err) }(time.Now())
+	return c.client.GetObject(ctx, params, optFns...)
+}
+
+func (c *metricsClient) GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (out *s3.GetObjectAttributesOutput, err error) {
+	defer func(start time.Time) { c.observe("GetObjectAttributes", start, err) }(time.Now())
+	return c.client.GetObjectAttributes(ctx, params, optFns...)
+}
+
+func (c *metricsClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (out *s3.DeleteObjectOutput, err error) {
+	defer func(start time.Time) { c.observe("DeleteObject", start, err) }(time.Now())
+	return c.client.DeleteObject(ctx, params, optFns...)
+}
+
+func (c *metricsClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (out *s3.DeleteObjectsOutput, err error) {
+	defer func(start time.Time) { c.observe("DeleteObjects", start, err) }(time.Now())
+	return c.client.DeleteObjects(ctx, params, optFns...)
+}
+
+func (c *metricsClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (out *s3.ListObjectsV2Output, err error) {
+	defer func(start time.Time) { c.observe("ListObjectsV2", start, err) }(time.Now())
+	return c.client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func (c *metricsClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (out *s3.ListObjectVersionsOutput, err error) {
+	defer func(start time.Time) { c.observe("ListObjectVersions", start, err) }(time.Now())
+	return c.client.ListObjectVersions(ctx, params, optFns...)
+}
+
+func (c *metricsClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (out *s3.UploadPartOutput, err error) {
+	defer func(start time.Time) { c.observe("UploadPart", start, err) }(time.Now())
+	return c.client.UploadPart(ctx, params, optFns...)
+}
+
+func (c *metricsClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (out *s3.UploadPartCopyOutput, err error) {
+	defer func(start time.Time) { c.observe("UploadPartCopy", start, err) }(time.Now())
+	return c.client.UploadPartCopy(ctx, params, optFns...)
+}
+
+func (c *metricsClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (out *s3.CreateMultipartUploadOutput, err error) {
+	defer func(start time.Time) { c.observe("CreateMultipartUpload", start, err) }(time.Now())
+	return c.client.CreateMultipartUpload(ctx, params, optFns...)
+}
+
+func (c *metricsClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (out *s3.CompleteMultipartUploadOutput, err error) {
+	defer func(start time.Time) { c.observe("CompleteMultipartUpload", start, err) }(time.Now())
+	return c.client.CompleteMultipartUpload(ctx, params, optFns...)
+}
+
+func (c *metricsClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (out *s3.AbortMultipartUploadOutput, err error) {
+	defer func(start time.Time) { c.observe("AbortMultipartUpload", start, err) }(time.Now())
+	return c.client.AbortMultipartUpload(ctx, params, optFns...)
+}
@@ -0,0 +1,167 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRecorder struct {
+	ops   map[string]int
+	errs  map[string]int
+	bytes map[string]int64
+	mu    sync.Mutex
+}
+
+func newFakeRecorder() *fakeRecorder {
+	return &fakeRecorder{
+		ops:   make(map[string]int),
+		errs:  make(map[string]int),
+		bytes: make(map[string]int64),
+	}
+}
+
+func (r *fakeRecorder) ObserveOp(op string, _ time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ops[op]++
+	if err != nil {
+		r.errs[op]++
+	}
+}
+
+func (r *fakeRecorder) AddBytes(op string, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bytes[op] += n
+}
+
+func TestMetrics(t *testing.T) {
+	recorder := newFakeRecorder()
+	fsys, _ := newTestFs(t, WithMetrics(recorder))
+
+	w, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.OpenReader("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("OpenReader() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	wantOps := map[string]int{"ListObjectsV2": 2, "PutObject": 1, "GetObject": 2}
+	for op, want := range wantOps {
+		if got := recorder.ops[op]; got != want {
+			t.Errorf("ObserveOp(%s) calls = %d, want %d", op, got, want)
+		}
+	}
+
+	if got := recorder.errs["GetObject"]; got != 1 {
+		t.Errorf("GetObject errors = %d, want 1", got)
+	}
+
+	wantBytes := map[string]int64{BytesRead: 7, BytesWritten: 7}
+	for op, want := range wantBytes {
+		if got := recorder.bytes[op]; got != want {
+			t.Errorf("AddBytes(%s) = %d, want %d", op, got, want)
+		}
+	}
+}
+
+func TestMetricsBaseClient(t *testing.T) {
+	fsys, client := newTestFs(t, WithMetrics(newFakeRecorder()))
+
+	if got := baseClient(fsys.client); got != client {
+		t.Errorf("baseClient() = %T, want %T", got, client)
+	}
+}
+
+type fakeMetrics struct {
+	ops       map[string]int
+	bytes     map[string]int64
+	latencies map[string]int
+	mu        sync.Mutex
+}
+
+func (m *fakeMetrics) IncOp(op string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ops[op]++
+}
+
+func (m *fakeMetrics) ObserveBytes(op string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bytes[op] += n
+}
+
+func (m *fakeMetrics) ObserveLatency(op string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latencies[op]++
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	metrics := &fakeMetrics{
+		ops:       make(map[string]int),
+		bytes:     make(map[string]int64),
+		latencies: make(map[string]int),
+	}
+	fsys, client := newTestFs(t, WithMetrics(MetricsRecorder(metrics)))
+	client.Put("test", "file", []byte("content"))
+
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range []string{"ListObjectsV2", "GetObject"} {
+		if got := metrics.ops[op]; got != 1 {
+			t.Errorf("IncOp(%s) calls = %d, want 1", op, got)
+		}
+		if got := metrics.latencies[op]; got != 1 {
+			t.Errorf("ObserveLatency(%s) calls = %d, want 1", op, got)
+		}
+	}
+
+	if got := metrics.bytes[BytesRead]; got != 7 {
+		t.Errorf("ObserveBytes(%s) = %d, want 7", BytesRead, got)
+	}
+}
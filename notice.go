@@ -0,0 +1,21 @@
+package s3fs
+
+// WithFallbackNotice sets the function called when an operation takes a path other than its default,
+// with the operation and the reason, to help tuning part sizes and diagnosing endpoints:
+//   - a write smaller than the upload part size is sent with a single PutObject
+//     instead of a multipart upload, except for CreateSized which asks for it
+//   - a copy larger than the CopyObject limit is made with a multipart copy
+//
+// Implementations must be safe for concurrent use.
+func WithFallbackNotice(fn func(op, reason string)) Option {
+	return func(f *Fs) {
+		f.fallbackNotice = fn
+	}
+}
+
+// notice reports a fallback of op to the function set by WithFallbackNotice.
+func (f *Fs) notice(op, reason string) {
+	if f.fallbackNotice != nil {
+		f.fallbackNotice(op, reason)
+	}
+}
@@ -0,0 +1,67 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestWithFallbackNotice(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  []string
+		size  int
+		sized bool
+	}{
+		{name: "small", size: 10, want: []string{"write: smaller than the part size, uploaded with PutObject"}},
+		{name: "multipart", size: MinPartSize + 1},
+		// a single part is chosen, not fallen back to
+		{name: "sized", size: 10, sized: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu      sync.Mutex
+				notices []string
+			)
+
+			fsys, client := newTestFs(t, WithFallbackNotice(func(op, reason string) {
+				mu.Lock()
+				defer mu.Unlock()
+				notices = append(notices, op+": "+reason)
+			}))
+
+			create := func() (*File, error) { return fsys.Create("file") }
+			if tt.sized {
+				create = func() (*File, error) { return fsys.CreateSized(context.Background(), "file", int64(tt.size)) }
+			}
+
+			f, err := create()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Write(bytes.Repeat([]byte("a"), tt.size)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if fmt.Sprint(notices) != fmt.Sprint(tt.want) {
+				t.Errorf("notices = %v, want %v", notices, tt.want)
+			}
+
+			if obj, _ := client.Object("test", "file"); len(obj.Data) != tt.size {
+				t.Errorf("size = %d, want %d", len(obj.Data), tt.size)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// OpenFile opens the named file with the os.OpenFile flags, perm is ignored.
+// O_RDONLY streams the object like Open, and O_WRONLY of a new object
+// or with O_TRUNC streams the writes like Create.
+// Other modes, such as O_RDWR or O_APPEND of an existing object, work on a local copy
+// in the temporary directory, uploaded on Close when modified.
+// O_CREATE|O_EXCL returns fs.ErrExist when the object exists,
+// and the upload only succeeds if it still does not.
+func (f *Fs) OpenFile(ctx context.Context, name string, flag int, _ fs.FileMode) (*File, error) {
+	info, err := f.StatWithContext(ctx, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	exists := err == nil
+
+	readOnly := flag&(os.O_WRONLY|os.O_RDWR) == 0
+
+	switch {
+	case exists && info.IsDir() && readOnly:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
+
+	case exists && info.IsDir():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("%w: %w", ErrIsDirectory, fs.ErrExist)}
+
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+
+	case !exists && (readOnly || flag&os.O_CREATE == 0):
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var opts []WriteOption
+	if flag&os.O_EXCL != 0 {
+		opts = append(opts, WithIfNoneMatch())
+	}
+
+	file := &File{
+		ctx:  ctx,
+		fs:   f,
+		info: info,
+	}
+
+	if readOnly {
+		return file, file.openReaderAt(ctx, 0)
+	}
+
+	if err := f.checkKey("open", name); err != nil {
+		return nil, err
+	}
+
+	f.invalidate(f.cleanPath(name))
+
+	// nothing of the object is kept, the writes are streamed
+	if flag&os.O_WRONLY != 0 && (!exists || flag&os.O_TRUNC != 0) {
+		file.info = regularFileInfo(f.cleanPath(name), 0, f.now())
+		return file, file.openWriter(ctx, opts...)
+	}
+
+	if !exists {
+		file.info = regularFileInfo(f.cleanPath(name), 0, f.now())
+	}
+
+	return file, file.openBuffer(ctx, flag, exists && flag&os.O_TRUNC == 0, opts)
+}
+
+// openBuffer makes a local copy of the file, holding its content when download is set.
+// A new or truncated file is uploaded on Close even when not written.
+func (f *File) openBuffer(ctx context.Context, flag int, download bool, opts []WriteOption) error {
+	buffer, err := os.CreateTemp(f.fs.tempDir, "s3fs-")
+	if err != nil {
+		return err
+	}
+
+	if download {
+		if err = f.download(ctx, buffer); err == nil {
+			_, err = buffer.Seek(0, io.SeekStart)
+		}
+	}
+
+	if err != nil {
+		_ = buffer.Close()
+		_ = os.Remove(buffer.Name())
+		return err
+	}
+
+	f.buffer = buffer
+	f.bufferFlag = flag
+	f.bufferOpts = opts
+	f.dirty = !download
+
+	return nil
+}
+
+// download copies the content of the file to w.
+func (f *File) download(ctx context.Context, w io.Writer) error {
+	if f.fs.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.fs.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.fs.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.fs.bucket),
+		Key:    aws.String(f.fs.withPrefix(f.Name())),
+	})
+	if err != nil {
+		return mapError(err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	n, err := io.Copy(w, f.fs.limitReader(ctx, res.Body))
+	f.fs.addBytes(BytesRead, int(n))
+
+	return err
+}
+
+// flushBuffer removes the local copy of the file,
+// after writing its content to a new upload when modified.
+func (f *File) flushBuffer() error {
+	buffer := f.buffer
+	f.buffer = nil
+
+	defer func() {
+		_ = buffer.Close()
+		_ = os.Remove(buffer.Name())
+	}()
+
+	if !f.dirty {
+		return nil
+	}
+
+	if _, err := buffer.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var o writeOptions
+	for _, opt := range f.bufferOpts {
+		opt(&o)
+	}
+
+	if o.deterministicParts {
+		info, err := buffer.Stat()
+		if err != nil {
+			return err
+		}
+
+		if o.size != nil && info.Size() != *o.size {
+			return &fs.PathError{Op: "close", Path: f.Name(), Err: fmt.Errorf("wrote %d bytes of %d: %w", info.Size(), *o.size, fs.ErrInvalid)}
+		}
+
+		// the uploader reads the parts of a seekable body at fixed offsets
+		err = f.upload(f.ctx, buffer, o)
+		f.fs.invalidate(f.Name())
+		if err != nil {
+			return err
+		}
+		f.fs.addBytes(BytesWritten, int(info.Size()))
+
+		f.refreshInfo(info.Size())
+
+		return nil
+	}
+
+	if err := f.openWriter(f.ctx, f.bufferOpts...); err != nil {
+		return err
+	}
+
+	n, err := io.Copy(f.writer, buffer)
+	f.fs.addBytes(BytesWritten, int(n))
+	if err != nil {
+		// aborts the upload
+		f.writerCancelFn()
+		_ = f.writer.Close()
+		<-f.writerDone
+		f.writerDone = nil
+		return err
+	}
+
+	return nil
+}
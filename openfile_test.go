@@ -0,0 +1,197 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestOpenFile(t *testing.T) {
+	tests := []struct {
+		write   func(*File) error
+		name    string
+		want    string
+		flag    int
+		exists  bool
+		wantPut bool
+	}{
+		{
+			name:    "truncate existing",
+			flag:    os.O_WRONLY | os.O_TRUNC,
+			exists:  true,
+			write:   writeString("new"),
+			want:    "new",
+			wantPut: true,
+		},
+		{
+			name:    "create",
+			flag:    os.O_WRONLY | os.O_CREATE,
+			write:   writeString("new"),
+			want:    "new",
+			wantPut: true,
+		},
+		{
+			name:    "create exclusive",
+			flag:    os.O_RDWR | os.O_CREATE | os.O_EXCL,
+			write:   func(*File) error { return nil },
+			want:    "",
+			wantPut: true,
+		},
+		{
+			name:    "append",
+			flag:    os.O_WRONLY | os.O_APPEND,
+			exists:  true,
+			write:   writeString("-new"),
+			want:    "content-new",
+			wantPut: true,
+		},
+		{
+			name:   "read write",
+			flag:   os.O_RDWR,
+			exists: true,
+			write: func(f *File) error {
+				b := make([]byte, 3)
+				if _, err := io.ReadFull(f, b); err != nil || string(b) != "con" {
+					return errors.New("unexpected read " + string(b))
+				}
+				_, err := f.Write([]byte("TENT!"))
+				return err
+			},
+			want:    "conTENT!",
+			wantPut: true,
+		},
+		{
+			name:    "read write unmodified",
+			flag:    os.O_RDWR,
+			exists:  true,
+			write:   func(*File) error { return nil },
+			want:    "content",
+			wantPut: false,
+		},
+		{
+			name:    "read write truncate",
+			flag:    os.O_RDWR | os.O_TRUNC,
+			exists:  true,
+			write:   func(f *File) error { return f.Truncate(2) },
+			want:    "\x00\x00",
+			wantPut: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+			if tt.exists {
+				client.Put("test", "file", []byte("content"))
+			}
+
+			f, err := fsys.OpenFile(context.Background(), "file", tt.flag, 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.write(f); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			obj, found := client.Object("test", "file")
+			if !found || string(obj.Data) != tt.want {
+				t.Errorf("content = %q, want %q", obj.Data, tt.want)
+			}
+
+			if got := client.Count("PutObject") > 0; got != tt.wantPut {
+				t.Errorf("uploaded = %v, want %v", got, tt.wantPut)
+			}
+		})
+	}
+}
+
+func writeString(s string) func(*File) error {
+	return func(f *File) error {
+		_, err := io.WriteString(f, s)
+		return err
+	}
+}
+
+func TestOpenFileErrors(t *testing.T) {
+	tests := []struct {
+		wantErr error
+		name    string
+		file    string
+		flag    int
+	}{
+		{
+			name:    "exclusive existing",
+			file:    "file",
+			flag:    os.O_WRONLY | os.O_CREATE | os.O_EXCL,
+			wantErr: fs.ErrExist,
+		},
+		{
+			name:    "write missing",
+			file:    "missing",
+			flag:    os.O_WRONLY,
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "read missing",
+			file:    "missing",
+			flag:    os.O_RDONLY | os.O_CREATE,
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "write directory",
+			file:    "dir",
+			flag:    os.O_WRONLY | os.O_TRUNC,
+			wantErr: fs.ErrExist,
+		},
+		{
+			name:    "read directory",
+			file:    "dir",
+			flag:    os.O_RDONLY,
+			wantErr: fs.ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t)
+			client.Put("test", "file", []byte("content"))
+			client.Put("test", "dir/file", nil)
+
+			if _, err := fsys.OpenFile(context.Background(), tt.file, tt.flag, 0o644); !errors.Is(err, tt.wantErr) {
+				t.Errorf("OpenFile() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenFileReadOnly(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("content"))
+
+	f, err := fsys.OpenFile(context.Background(), "file", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "content" {
+		t.Errorf("content = %q, want %q", got, "content")
+	}
+
+	if _, err := f.Write([]byte("x")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Write() error = %v, want %v", err, fs.ErrClosed)
+	}
+}
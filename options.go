@@ -0,0 +1,90 @@
+package s3fs
+
+import (
+	"context"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// WithS3Options sets functions applied to the s3.Options of every S3 call,
+// before the ones of the call, such as to tweak the HTTP behavior of a Fs
+// without building another client.
+func WithS3Options(optFns ...func(*s3.Options)) Option {
+	return func(f *Fs) {
+		f.s3Options = append(f.s3Options, optFns...)
+	}
+}
+
+// optionsClient applies the s3.Options functions set by WithS3Options to every call.
+type optionsClient struct {
+	client s3ApiClient
+	optFns []func(*s3.Options)
+}
+
+func (c *optionsClient) unwrap() s3ApiClient { return c.client }
+
+// with returns the functions of the Fs followed by the ones of the call.
+func (c *optionsClient) with(optFns []func(*s3.Options)) []func(*s3.Options) {
+	return append(slices.Clip(c.optFns), optFns...)
+}
+
+func (c *optionsClient) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return c.client.HeadBucket(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return c.client.HeadObject(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return c.client.CopyObject(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return c.client.PutObject(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return c.client.GetObject(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error) {
+	return c.client.GetObjectAttributes(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return c.client.DeleteObject(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return c.client.DeleteObjects(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return c.client.ListObjectsV2(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return c.client.ListObjectVersions(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return c.client.UploadPart(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return c.client.UploadPartCopy(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return c.client.CreateMultipartUpload(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return c.client.CompleteMultipartUpload(ctx, params, c.with(optFns)...)
+}
+
+func (c *optionsClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return c.client.AbortMultipartUpload(ctx, params, c.with(optFns)...)
+}
@@ -0,0 +1,82 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+// optionsRecorder records the operations called with options using path style.
+type optionsRecorder struct {
+	*s3fstest.Client
+	ops []string
+	mu  sync.Mutex
+}
+
+func (c *optionsRecorder) record(op string, optFns []func(*s3.Options)) {
+	var o s3.Options
+	for _, fn := range optFns {
+		fn(&o)
+	}
+
+	if o.UsePathStyle {
+		c.mu.Lock()
+		c.ops = append(c.ops, op)
+		c.mu.Unlock()
+	}
+}
+
+func (c *optionsRecorder) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.record("ListObjectsV2", optFns)
+	return c.Client.ListObjectsV2(ctx, in, optFns...)
+}
+
+func (c *optionsRecorder) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.record("GetObject", optFns)
+	return c.Client.GetObject(ctx, in, optFns...)
+}
+
+func TestWithS3Options(t *testing.T) {
+	mock := s3fstest.New()
+	mock.Put("test", "file", []byte("content"))
+
+	client := &optionsRecorder{Client: mock}
+	fsys := New(client, "test", WithS3Options(func(o *s3.Options) { o.UsePathStyle = true }), WithRetry(2, 0))
+
+	if _, err := fsys.ReadDir("."); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := fsys.OpenReader("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, op := range client.ops {
+		seen[op] = true
+	}
+
+	for _, op := range []string{"ListObjectsV2", "GetObject"} {
+		if !seen[op] {
+			t.Errorf("%s called without the options, got %v", op, client.ops)
+		}
+	}
+
+	if got := baseClient(fsys.client); got != client {
+		t.Errorf("baseClient() = %T, want %T", got, client)
+	}
+}
@@ -0,0 +1,57 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ReadPart opens the part partNumber of the named multipart object, returning its bytes and size.
+// ErrInvalidPart is returned for objects uploaded in a single part and out of range part numbers.
+// The caller must close the reader.
+func (f *Fs) ReadPart(name string, partNumber int) (io.ReadCloser, int64, error) {
+	return f.ReadPartWithContext(context.Background(), name, partNumber)
+}
+
+// ReadPartWithContext opens the part partNumber of the named multipart object, returning its bytes and size.
+// ErrInvalidPart is returned for objects uploaded in a single part and out of range part numbers.
+// The caller must close the reader.
+func (f *Fs) ReadPartWithContext(ctx context.Context, name string, partNumber int) (io.ReadCloser, int64, error) {
+	if partNumber < 1 || partNumber > MaxParts {
+		return nil, 0, &fs.PathError{Op: "readpart", Path: name, Err: ErrInvalidPart}
+	}
+
+	cancelFn := context.CancelFunc(func() {})
+	if f.timeout > 0 {
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+	}
+
+	res, err := f.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:     aws.String(f.bucket),
+		Key:        aws.String(f.withPrefix(name)),
+		PartNumber: aws.Int32(int32(partNumber)),
+	})
+	if err != nil {
+		cancelFn()
+		switch {
+		case isNotFound(err):
+			return nil, 0, &fs.PathError{Op: "readpart", Path: name, Err: fs.ErrNotExist}
+		case httpStatusCode(err) == http.StatusRequestedRangeNotSatisfiable:
+			return nil, 0, &fs.PathError{Op: "readpart", Path: name, Err: ErrInvalidPart}
+		}
+		return nil, 0, mapError(err)
+	}
+
+	// single part objects are returned whole for the part 1
+	if aws.ToInt32(res.PartsCount) < 1 {
+		_ = res.Body.Close()
+		cancelFn()
+		return nil, 0, &fs.PathError{Op: "readpart", Path: name, Err: ErrInvalidPart}
+	}
+
+	return &bodyReader{ReadCloser: res.Body, ctx: ctx, fs: f, cancelFn: cancelFn}, aws.ToInt64(res.ContentLength), nil
+}
@@ -0,0 +1,64 @@
+package s3fs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestReadPart(t *testing.T) {
+	fsys, client := newTestFs(t, WithUploadPartSize(5*1024*1024))
+	data := bytes.Repeat([]byte("0123456789abcdef"), 12*1024*1024/16)
+
+	w, err := fsys.Create("multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	client.Put("test", "single", []byte("content"))
+	client.Reset()
+
+	r, size, err := fsys.ReadPart("multipart", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 5*1024*1024 || !bytes.Equal(got, data[5*1024*1024:10*1024*1024]) {
+		t.Errorf("ReadPart() = %d bytes of size %d, want data[5MiB:10MiB]", len(got), size)
+	}
+
+	if in, ok := client.Calls()[0].Input.(*s3.GetObjectInput); !ok || aws.ToInt32(in.PartNumber) != 2 {
+		t.Errorf("GetObject PartNumber = %v, want 2", client.Calls()[0].Input)
+	}
+
+	for _, tt := range []struct {
+		name string
+		part int
+	}{
+		{name: "single", part: 1},
+		{name: "multipart", part: 4},
+		{name: "multipart", part: 0},
+	} {
+		if _, _, err := fsys.ReadPart(tt.name, tt.part); !errors.Is(err, ErrInvalidPart) {
+			t.Errorf("ReadPart(%s, %d) error = %v, want %v", tt.name, tt.part, err, ErrInvalidPart)
+		}
+	}
+}
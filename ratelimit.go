@@ -0,0 +1,107 @@
+package s3fs
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// WithRateLimit caps the bandwidth of the object bodies read and written by the Fs, such as for a background sync,
+// to bytesPerSecond, shared by its uploads and downloads.
+// In memory bodies, such as of ReplaceAtomically, are paced as a whole before they are sent.
+func WithRateLimit(bytesPerSecond int64) Option {
+	return func(f *Fs) {
+		if bytesPerSecond > 0 {
+			f.rateLimiter = &rateLimiter{rate: bytesPerSecond}
+		}
+	}
+}
+
+// rateLimiter paces transfers to rate bytes per second,
+// each transfer waiting until the bytes reserved before it are due.
+type rateLimiter struct {
+	next time.Time
+	rate int64
+	mu   sync.Mutex
+}
+
+// wait reserves n bytes and waits until they are due, or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	due := l.next
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(due))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitReader returns r paced by WithRateLimit, r itself without it.
+func (f *Fs) limitReader(ctx context.Context, r io.Reader) io.Reader {
+	if f.rateLimiter == nil {
+		return r
+	}
+
+	return &limitedReader{ctx: ctx, r: r, limiter: f.rateLimiter}
+}
+
+// waitRate waits until n bytes are due under WithRateLimit, returning at once without it.
+func (f *Fs) waitRate(ctx context.Context, n int) error {
+	if f.rateLimiter == nil {
+		return nil
+	}
+
+	return f.rateLimiter.wait(ctx, n)
+}
+
+// limitWriterAt returns w paced by WithRateLimit, w itself without it.
+func (f *Fs) limitWriterAt(ctx context.Context, w io.WriterAt) io.WriterAt {
+	if f.rateLimiter == nil {
+		return w
+	}
+
+	return &limitedWriterAt{ctx: ctx, w: w, limiter: f.rateLimiter}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
+
+type limitedWriterAt struct {
+	ctx     context.Context
+	w       io.WriterAt
+	limiter *rateLimiter
+}
+
+func (w *limitedWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if err := w.limiter.wait(w.ctx, len(p)); err != nil {
+		return 0, err
+	}
+
+	return w.w.WriteAt(p, off)
+}
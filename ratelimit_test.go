@@ -0,0 +1,134 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestRateLimit(t *testing.T) {
+	const (
+		rate = 512 * 1024
+		size = 256 * 1024
+	)
+
+	fsys, _ := newTestFs(t, WithRateLimit(rate))
+	data := bytes.Repeat([]byte("a"), size)
+	want := time.Duration(size) * time.Second / rate
+
+	start := time.Now()
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < want*9/10 {
+		t.Errorf("upload took %v, want at least %v", elapsed, want)
+	}
+
+	start = time.Now()
+
+	r, err := fsys.Open("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < want*9/10 {
+		t.Errorf("download took %v, want at least %v", elapsed, want)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Error("content mismatch")
+	}
+}
+
+func TestRateLimitMethods(t *testing.T) {
+	const (
+		rate = 1024 * 1024
+		size = 128 * 1024
+	)
+
+	data := bytes.Repeat([]byte("a"), size)
+	want := time.Duration(size) * time.Second / rate
+
+	tests := []struct {
+		fn   func(*Fs) error
+		name string
+	}{
+		{name: "OpenReader", fn: func(fsys *Fs) error {
+			r, err := fsys.OpenReader("file")
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			_, err = io.Copy(io.Discard, r)
+			return err
+		}},
+		{name: "OpenReaderAt", fn: func(fsys *Fs) error {
+			r, size, err := fsys.OpenReaderAt(context.Background(), "file")
+			if err != nil {
+				return err
+			}
+
+			_, err = r.ReadAt(make([]byte, size), 0)
+			return err
+		}},
+		{name: "Section", fn: func(fsys *Fs) error {
+			r, err := fsys.Section(context.Background(), "file", 0, size)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			_, err = io.Copy(io.Discard, r)
+			return err
+		}},
+		{name: "DownloadTo", fn: func(fsys *Fs) error {
+			_, err := fsys.DownloadTo(context.Background(), "file", manager.NewWriteAtBuffer(nil))
+			return err
+		}},
+		{name: "ReplaceAtomically", fn: func(fsys *Fs) error {
+			return fsys.ReplaceAtomically("file", data)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t, WithRateLimit(rate))
+			client.Put("test", "file", data)
+
+			start := time.Now()
+
+			if err := tt.fn(fsys); err != nil {
+				t.Fatal(err)
+			}
+
+			if elapsed := time.Since(start); elapsed < want*9/10 {
+				t.Errorf("took %v, want at least %v", elapsed, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// OpenReaderAt opens the named file for random access, returning a reader and the file size.
+// Unlike File, nothing is downloaded in the background: each ReadAt is a single ranged GetObject,
+// suited to sparse reads such as the footer and columns of a parquet file or the directory of a zip.
+// The reader holds no state besides ctx, used by every ReadAt, and is safe for concurrent use.
+// Every ReadAt reads the object as it was opened: once it is replaced, ReadAt fails with ErrPreconditionFailed.
+func (f *Fs) OpenReaderAt(ctx context.Context, name string) (io.ReaderAt, int64, error) {
+	if f.cleanPath(name) == "" {
+		return nil, 0, &fs.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
+	}
+
+	headCtx := ctx
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		headCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(headCtx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(name)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, 0, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return nil, 0, mapError(err)
+	}
+
+	size := aws.ToInt64(res.ContentLength)
+
+	return &rangeReaderAt{ctx: ctx, fs: f, name: name, key: f.withPrefix(name), etag: res.ETag, size: size}, size, nil
+}
+
+// rangeReaderAt reads the object with a ranged GetObject per ReadAt.
+type rangeReaderAt struct {
+	ctx  context.Context
+	fs   *Fs
+	etag *string
+	name string
+	key  string
+	size int64
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: r.name, Err: errors.New("negative offset")}
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	end := min(off+int64(len(p)), r.size)
+
+	ctx := r.ctx
+	if r.fs.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, r.fs.timeout)
+		defer cancelFn()
+	}
+
+	res, err := r.fs.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(r.fs.bucket),
+		Key:     aws.String(r.key),
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", off, end-1)),
+		IfMatch: r.etag,
+	})
+	if err != nil {
+		return 0, mapError(err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	n, err := io.ReadFull(res.Body, p[:end-off])
+	r.fs.addBytes(BytesRead, n)
+	if waitErr := r.fs.waitRate(ctx, n); waitErr != nil {
+		return n, waitErr
+	}
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return n, err
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestOpenReaderAt(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+	client.Put("test", "prefix/file", data)
+
+	r, size, err := fsys.OpenReaderAt(context.Background(), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != int64(len(data)) {
+		t.Fatalf("size = %d, want %d", size, len(data))
+	}
+
+	tests := []struct {
+		wantErr error
+		off     int64
+		length  int
+		want    int
+	}{
+		{off: 0, length: 16, want: 16},
+		{off: 700_000, length: 4096, want: 4096},
+		{off: 12_345, length: 1, want: 1},
+		{off: 1<<20 - 8, length: 8, want: 8},
+		{off: 1<<20 - 8, length: 16, want: 8, wantErr: io.EOF},
+		{off: 1 << 20, length: 16, want: 0, wantErr: io.EOF},
+	}
+
+	client.Reset()
+
+	for _, tt := range tests {
+		p := make([]byte, tt.length)
+
+		n, err := r.ReadAt(p, tt.off)
+		if n != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ReadAt(%d, %d) = (%d, %v), want (%d, %v)", tt.length, tt.off, n, err, tt.want, tt.wantErr)
+			continue
+		}
+
+		if !bytes.Equal(p[:n], data[tt.off:tt.off+int64(n)]) {
+			t.Errorf("ReadAt(%d, %d) content mismatch", tt.length, tt.off)
+		}
+	}
+
+	// a ranged request per read within the object, none past the end
+	calls := client.Calls()
+	if len(calls) != len(tests)-1 {
+		t.Fatalf("calls = %d, want %d", len(calls), len(tests)-1)
+	}
+
+	if in := calls[1].Input.(*s3.GetObjectInput); aws.ToString(in.Range) != "bytes=700000-704095" {
+		t.Errorf("Range = %s, want bytes=700000-704095", aws.ToString(in.Range))
+	}
+}
+
+func TestOpenReaderAtNotExist(t *testing.T) {
+	fsys, _ := newTestFs(t)
+
+	if _, _, err := fsys.OpenReaderAt(context.Background(), "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenReaderAt() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestOpenReaderAtReplaced(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("0123456789"))
+
+	r, _, err := fsys.OpenReaderAt(context.Background(), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := make([]byte, 4)
+	if _, err := r.ReadAt(p, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	client.Put("test", "file", []byte("abcdefghij"))
+
+	if n, err := r.ReadAt(p, 4); n != 0 || !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("ReadAt() = (%d, %v), want (0, %v)", n, err, ErrPreconditionFailed)
+	}
+}
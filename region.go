@@ -0,0 +1,192 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// bucketRegionHeader is the response header holding the region of a bucket,
+// including on the redirects of requests sent to another region.
+const bucketRegionHeader = "X-Amz-Bucket-Region"
+
+// WithRegionCheck checks the bucket is in the region of the client, see CheckRegion,
+// by NewValidated or else before the first S3 call.
+// When the regions differ, every call fails with the error of CheckRegion.
+// A check failing for another reason, such as a network error, is done again on the next call.
+func WithRegionCheck() Option {
+	return func(f *Fs) {
+		f.regionCheck = &regionCheckClient{}
+	}
+}
+
+// BucketRegion returns the region of the bucket, as reported by HeadBucket.
+func (f *Fs) BucketRegion(ctx context.Context) (string, error) {
+	return f.bucketRegion(ctx, f.client)
+}
+
+func (f *Fs) bucketRegion(ctx context.Context, client s3ApiClient) (string, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(f.bucket),
+	})
+	if err != nil {
+		// the redirect of a request sent to another region names the right one
+		var re *awshttp.ResponseError
+		if errors.As(err, &re) && re.Response != nil {
+			if region := re.Response.Header.Get(bucketRegionHeader); region != "" {
+				return region, nil
+			}
+		}
+		if isNotFound(err) {
+			return "", fmt.Errorf("%w: %w", ErrBucketNotFound, err)
+		}
+		return "", mapError(err)
+	}
+
+	return aws.ToString(res.BucketRegion), nil
+}
+
+// CheckRegion returns an error matching ErrInvalidConfig when the bucket is not in the region of the client,
+// which otherwise fails every call with a PermanentRedirect.
+// Clients not exposing their options, such as test doubles, are not checked.
+func (f *Fs) CheckRegion(ctx context.Context) error {
+	return f.checkRegion(ctx, f.client)
+}
+
+func (f *Fs) checkRegion(ctx context.Context, c s3ApiClient) error {
+	client, ok := baseClient(c).(interface{ Options() s3.Options })
+	if !ok || client.Options().Region == "" {
+		return nil
+	}
+
+	region, err := f.bucketRegion(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if configured := client.Options().Region; region != "" && region != configured {
+		return fmt.Errorf("%w: bucket %s is in region %s, client configured for %s", ErrInvalidConfig, f.bucket, region, configured)
+	}
+
+	return nil
+}
+
+// regionCheckClient checks the region of the bucket before the first call, see WithRegionCheck.
+type regionCheckClient struct {
+	client  s3ApiClient
+	checkFn func(context.Context) error
+	// err is the result of the check, once done.
+	err  error
+	mu   sync.Mutex
+	done bool
+}
+
+func (c *regionCheckClient) unwrap() s3ApiClient { return c.client }
+
+// check returns the result of the region check, running it unless it is known.
+func (c *regionCheckClient) check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done {
+		return c.err
+	}
+
+	err := c.checkFn(ctx)
+	if err == nil || errors.Is(err, ErrInvalidConfig) {
+		c.done, c.err = true, err
+	}
+
+	return err
+}
+
+// regionChecked calls fn unless the bucket is in another region.
+func regionChecked[T any](ctx context.Context, c *regionCheckClient, fn func() (T, error)) (T, error) {
+	if err := c.check(ctx); errors.Is(err, ErrInvalidConfig) {
+		var zero T
+		return zero, err
+	}
+
+	return fn()
+}
+
+func (c *regionCheckClient) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.HeadBucketOutput, error) { return c.client.HeadBucket(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.HeadObjectOutput, error) { return c.client.HeadObject(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.CopyObjectOutput, error) { return c.client.CopyObject(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.PutObjectOutput, error) { return c.client.PutObject(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.GetObjectOutput, error) { return c.client.GetObject(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.GetObjectAttributesOutput, error) {
+		return c.client.GetObjectAttributes(ctx, params, optFns...)
+	})
+}
+
+func (c *regionCheckClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.DeleteObjectOutput, error) { return c.client.DeleteObject(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.DeleteObjectsOutput, error) { return c.client.DeleteObjects(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return regionChecked(ctx, c, func() (*s3.ListObjectsV2Output, error) { return c.client.ListObjectsV2(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.ListObjectVersionsOutput, error) {
+		return c.client.ListObjectVersions(ctx, params, optFns...)
+	})
+}
+
+func (c *regionCheckClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.UploadPartOutput, error) { return c.client.UploadPart(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.UploadPartCopyOutput, error) { return c.client.UploadPartCopy(ctx, params, optFns...) })
+}
+
+func (c *regionCheckClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.CreateMultipartUploadOutput, error) {
+		return c.client.CreateMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *regionCheckClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.CompleteMultipartUploadOutput, error) {
+		return c.client.CompleteMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *regionCheckClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return regionChecked(ctx, c, func() (*s3.AbortMultipartUploadOutput, error) {
+		return c.client.AbortMultipartUpload(ctx, params, optFns...)
+	})
+}
@@ -0,0 +1,133 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+// regionClient is configured for region, redirecting as S3 does when the bucket is elsewhere.
+type regionClient struct {
+	*s3fstest.Client
+	region string
+}
+
+func (c regionClient) Options() s3.Options {
+	return s3.Options{Region: c.region}
+}
+
+func (c regionClient) HeadBucket(ctx context.Context, in *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if c.Client.Region == c.region {
+		return c.Client.HeadBucket(ctx, in, optFns...)
+	}
+
+	err := s3fstest.Error("HeadBucket", http.StatusMovedPermanently, &smithy.GenericAPIError{Code: "PermanentRedirect"})
+
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) {
+		re.Response.Header.Set("X-Amz-Bucket-Region", c.Client.Region)
+	}
+
+	return nil, err
+}
+
+func TestRegionCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  string
+		wantErr string
+	}{
+		{name: "same region", region: "eu-west-1"},
+		{name: "other region", region: "us-east-1", wantErr: "bucket test is in region eu-west-1, client configured for us-east-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := s3fstest.New()
+			mock.CreateBucket("test")
+			mock.Region = "eu-west-1"
+
+			fsys, err := NewValidated(regionClient{Client: mock, region: tt.region}, "test", WithRegionCheck())
+
+			if tt.wantErr == "" {
+				if err != nil || fsys == nil {
+					t.Fatalf("NewValidated() = (%v, %v), want a Fs", fsys, err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewValidated() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBucketRegion(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Region = "ap-south-1"
+
+	region, err := fsys.BucketRegion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if region != "ap-south-1" {
+		t.Errorf("BucketRegion() = %q, want %q", region, "ap-south-1")
+	}
+
+	missing := New(client, "missing")
+	if _, err := missing.BucketRegion(context.Background()); !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("BucketRegion() error = %v, want %v", err, ErrBucketNotFound)
+	}
+}
+
+func TestRegionCheckOnFirstCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  string
+		wantErr string
+	}{
+		{name: "same region", region: "eu-west-1"},
+		{name: "other region", region: "us-east-1", wantErr: "bucket test is in region eu-west-1, client configured for us-east-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := s3fstest.New()
+			mock.CreateBucket("test")
+			mock.Region = "eu-west-1"
+			mock.Put("test", "file.txt", nil)
+
+			fsys := New(regionClient{Client: mock, region: tt.region}, "test", WithRegionCheck())
+
+			for i := 0; i < 2; i++ {
+				_, err := fsys.Stat("file.txt")
+
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+
+				if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Stat() error = %v, want %q", err, tt.wantErr)
+				}
+			}
+
+			// the check is done once, the redirects are not recorded by the mock
+			if got := mock.Count("HeadBucket"); tt.wantErr == "" && got != 1 {
+				t.Errorf("HeadBucket calls = %d, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package s3fs
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxDeleteKeys is the maximum number of keys of a DeleteObjects request.
+const maxDeleteKeys = 1000
+
+// RemoveFiles removes the named files with DeleteObjects, in batches of 1000 keys.
+// The names are not checked, missing files are ignored as with Remove.
+// When some keys fail, the others are still deleted and a *BatchDeleteError is returned.
+func (f *Fs) RemoveFiles(ctx context.Context, names ...string) error {
+	return f.deleteKeys(ctx, names)
+}
+
+// deleteKeys deletes the named objects, collecting the failed ones in a *BatchDeleteError.
+func (f *Fs) deleteKeys(ctx context.Context, names []string) error {
+	failures := make(map[string]string)
+
+	for len(names) > 0 {
+		batch := names[:min(len(names), maxDeleteKeys)]
+		names = names[len(batch):]
+
+		if err := f.deleteBatch(ctx, batch, failures); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return &BatchDeleteError{Failures: failures}
+	}
+
+	return nil
+}
+
+// deleteBatch deletes up to maxDeleteKeys names, adding the failed ones to failures.
+func (f *Fs) deleteBatch(ctx context.Context, names []string, failures map[string]string) error {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	objects := make([]types.ObjectIdentifier, 0, len(names))
+	keyNames := make(map[string]string, len(names))
+	for _, name := range names {
+		key := f.withPrefix(name)
+		keyNames[key] = name
+		objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+	}
+
+	res, err := f.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(f.bucket),
+		Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	})
+	for _, name := range names {
+		f.invalidate(f.cleanPath(name))
+	}
+	if err != nil {
+		return mapError(err)
+	}
+
+	for _, e := range res.Errors {
+		failures[keyNames[aws.ToString(e.Key)]] = aws.ToString(e.Code)
+	}
+
+	return nil
+}
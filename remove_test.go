@@ -0,0 +1,98 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+// partialDeleteClient reports the keys listed in failures as failed DeleteObjects entries.
+type partialDeleteClient struct {
+	*s3fstest.Client
+	failures map[string]string
+}
+
+func (c *partialDeleteClient) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	var (
+		objects []types.ObjectIdentifier
+		errs    []types.Error
+	)
+
+	for _, obj := range in.Delete.Objects {
+		if code, found := c.failures[aws.ToString(obj.Key)]; found {
+			errs = append(errs, types.Error{Key: obj.Key, Code: aws.String(code)})
+			continue
+		}
+		objects = append(objects, obj)
+	}
+
+	out, err := c.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: in.Bucket,
+		Delete: &types.Delete{Objects: objects, Quiet: in.Delete.Quiet},
+	}, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	out.Errors = errs
+
+	return out, nil
+}
+
+func TestRemoveFiles(t *testing.T) {
+	fsys, client := newTestFs(t, WithPrefix("prefix"))
+
+	var names []string
+	for i := 0; i < 2500; i++ {
+		name := fmt.Sprintf("dir/file%04d", i)
+		client.Put("test", "prefix/"+name, nil)
+		names = append(names, name)
+	}
+
+	if err := fsys.RemoveFiles(context.Background(), names...); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := client.Keys("test"); len(keys) != 0 {
+		t.Errorf("keys = %d, want 0", len(keys))
+	}
+
+	if got := client.Count("DeleteObjects"); got != 3 {
+		t.Errorf("DeleteObjects calls = %d, want 3", got)
+	}
+}
+
+func TestRemoveFilesPartialFailure(t *testing.T) {
+	mock := s3fstest.New()
+	for _, key := range []string{"prefix/a", "prefix/b", "prefix/c", "prefix/d"} {
+		mock.Put("test", key, nil)
+	}
+
+	client := &partialDeleteClient{Client: mock, failures: map[string]string{
+		"prefix/b": "AccessDenied",
+		"prefix/d": "InternalError",
+	}}
+	fsys := New(client, "test", WithPrefix("prefix"))
+
+	err := fsys.RemoveFiles(context.Background(), "a", "b", "c", "d")
+
+	var batchErr *BatchDeleteError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("RemoveFiles() error = %v, want *BatchDeleteError", err)
+	}
+
+	want := map[string]string{"b": "AccessDenied", "d": "InternalError"}
+	if fmt.Sprint(batchErr.Failures) != fmt.Sprint(want) {
+		t.Errorf("Failures = %v, want %v", batchErr.Failures, want)
+	}
+
+	if keys := mock.Keys("test"); fmt.Sprint(keys) != "[prefix/b prefix/d]" {
+		t.Errorf("keys = %v, want [prefix/b prefix/d]", keys)
+	}
+}
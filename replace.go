@@ -0,0 +1,101 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ReplaceAtomically replaces the content of the named file with data,
+// uploading it to a temporary file of the same directory,
+// copied over the named file with a server side copy before being deleted.
+// A single PutObject is already atomic, readers never see part of it,
+// this is meant for writes built in several steps, as the last one.
+func (f *Fs) ReplaceAtomically(name string, data []byte) error {
+	return f.ReplaceAtomicallyWithContext(context.Background(), name, data)
+}
+
+// ReplaceAtomicallyWithContext replaces the content of the named file with data,
+// uploading it to a temporary file of the same directory,
+// copied over the named file with a server side copy before being deleted.
+// A single PutObject is already atomic, readers never see part of it,
+// this is meant for writes built in several steps, as the last one.
+func (f *Fs) ReplaceAtomicallyWithContext(ctx context.Context, name string, data []byte) error {
+	if err := f.checkKey("replace", name); err != nil {
+		return err
+	}
+
+	// the root is a directory, even without keys
+	if f.cleanPath(name) == "" {
+		return &fs.PathError{Op: "replace", Path: name, Err: ErrIsDirectory}
+	}
+
+	isDir, err := f.hasKeysUnder(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if isDir {
+		return &fs.PathError{Op: "replace", Path: name, Err: ErrIsDirectory}
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	name = f.cleanPath(name)
+	tmp := f.join(f.dir(name), fmt.Sprintf(".%s.%s.tmp", path.Base(name), hex.EncodeToString(suffix)))
+
+	if err := f.putObject(ctx, tmp, name, data); err != nil {
+		return err
+	}
+
+	err = f.copyKey(ctx, f.withPrefix(tmp), name)
+	f.invalidate(name)
+
+	// the temporary file is deleted even if the caller gave up
+	_, deleteErr := f.client.DeleteObject(context.WithoutCancel(ctx), &s3.DeleteObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(tmp)),
+	})
+	f.invalidate(tmp)
+
+	if err != nil {
+		return err
+	}
+
+	return mapError(deleteErr)
+}
+
+// putObject uploads data to the named file, with the Content-Type of contentName.
+func (f *Fs) putObject(ctx context.Context, name, contentName string, data []byte) error {
+	if err := f.waitRate(ctx, len(data)); err != nil {
+		return err
+	}
+
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	_, err := f.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:            aws.String(f.bucket),
+		Key:               aws.String(f.withPrefix(name)),
+		Body:              bytes.NewReader(data),
+		ACL:               f.acl,
+		ContentType:       f.contentType(contentName),
+		ChecksumAlgorithm: f.uploadChecksum,
+	})
+	f.invalidate(name)
+
+	return mapError(err)
+}
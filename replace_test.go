@@ -0,0 +1,97 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+// snapshotClient records the content of the target key after each write.
+type snapshotClient struct {
+	*s3fstest.Client
+	target    string
+	snapshots []string
+}
+
+func (c *snapshotClient) snapshot() {
+	obj, found := c.Object("test", c.target)
+	if !found {
+		c.snapshots = append(c.snapshots, "")
+		return
+	}
+	c.snapshots = append(c.snapshots, string(obj.Data))
+}
+
+func (c *snapshotClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	defer c.snapshot()
+	return c.Client.PutObject(ctx, in, optFns...)
+}
+
+func (c *snapshotClient) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	defer c.snapshot()
+	return c.Client.CopyObject(ctx, in, optFns...)
+}
+
+func (c *snapshotClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	defer c.snapshot()
+	return c.Client.DeleteObject(ctx, in, optFns...)
+}
+
+func TestReplaceAtomically(t *testing.T) {
+	mock := s3fstest.New()
+	mock.Put("test", "dir/file", []byte("old"))
+	client := &snapshotClient{Client: mock, target: "dir/file"}
+	fsys := New(client, "test")
+
+	data := bytes.Repeat([]byte("new"), 1024)
+	if err := fsys.ReplaceAtomically("dir/file", data); err != nil {
+		t.Fatal(err)
+	}
+
+	// the put of the temporary file, the copy and the delete
+	if len(client.snapshots) != 3 {
+		t.Fatalf("writes = %d, want 3", len(client.snapshots))
+	}
+
+	for i, s := range client.snapshots {
+		if s != "old" && s != string(data) {
+			t.Errorf("content after write %d = %.16q, want old or new content", i+1, s)
+		}
+	}
+
+	if got := mock.Keys("test"); len(got) != 1 || got[0] != "dir/file" {
+		t.Errorf("keys = %v, want [dir/file]", got)
+	}
+
+	got, err := fs.ReadFile(fsys, "dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Errorf("content = %.16q, want %.16q", got, data)
+	}
+}
+
+func TestReplaceAtomicallyDirectory(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "dir/file", nil)
+
+	if err := fsys.ReplaceAtomically("dir", []byte("data")); !errors.Is(err, ErrIsDirectory) {
+		t.Errorf("ReplaceAtomically() error = %v, want %v", err, ErrIsDirectory)
+	}
+
+	if err := fsys.ReplaceAtomically(".", []byte("data")); !errors.Is(err, ErrIsDirectory) {
+		t.Errorf("ReplaceAtomically(.) error = %v, want %v", err, ErrIsDirectory)
+	}
+
+	if keys := client.Keys("test"); len(keys) != 1 {
+		t.Errorf("keys = %v, want [dir/file]", keys)
+	}
+}
@@ -0,0 +1,217 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// retryableCodes are the S3 error codes of transient failures.
+var retryableCodes = map[string]struct{}{
+	"SlowDown":             {},
+	"RequestTimeout":       {},
+	"InternalError":        {},
+	"ServiceUnavailable":   {},
+	"Throttling":           {},
+	"ThrottlingException":  {},
+	"RequestLimitExceeded": {},
+}
+
+// WithRetry retries the S3 operations failing with a transient error, such as SlowDown or a 5xx status,
+// up to maxAttempts times, waiting base and then doubling it between attempts.
+// It comes on top of the retries of the SDK, for long listings and transfers.
+// Uploads are only retried when the body can be rewound, which is the case of the ones made by File.
+func WithRetry(maxAttempts int, base time.Duration) Option {
+	return func(f *Fs) {
+		f.retryAttempts = maxAttempts
+		f.retryBase = base
+	}
+}
+
+// WithRenameRetry retries the delete of the source of Rename failing with a transient error,
+// up to maxAttempts times, waiting base and then doubling it between attempts.
+// When the delete still fails, Rename returns ErrRenameCleanupFailed.
+// The delete is retried as set here instead of as set by WithRetry.
+func WithRenameRetry(maxAttempts int, base time.Duration) Option {
+	return func(f *Fs) {
+		f.renameAttempts = maxAttempts
+		f.renameRetryBase = base
+	}
+}
+
+// WithRetryClassifier sets the function deciding which errors are transient,
+// used by WithRetry and WithRenameRetry in place of IsRetryable.
+// It adapts the retries to the error codes of S3 compatible endpoints, and may call IsRetryable.
+func WithRetryClassifier(fn func(error) bool) Option {
+	return func(f *Fs) {
+		f.retryClassifier = fn
+	}
+}
+
+// IsRetryable reports whether err is a transient S3 failure,
+// such as SlowDown or a 5xx status. It is the default retry classifier.
+func IsRetryable(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if _, found := retryableCodes[apiErr.ErrorCode()]; found {
+			return true
+		}
+	}
+
+	switch httpStatusCode(err) {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// retryClient retries the operations of a client failing with a transient error.
+type retryClient struct {
+	client s3ApiClient
+	// retryable classifies the errors, defaults to IsRetryable
+	retryable   func(error) bool
+	maxAttempts int
+	base        time.Duration
+}
+
+func (c *retryClient) unwrap() s3ApiClient { return c.client }
+
+// retry calls fn until it succeeds, fails with an error that is not transient,
+// or the attempts are exhausted. rewind is called before each new attempt, when set.
+func retry[T any](ctx context.Context, c *retryClient, rewind func() error, fn func() (T, error)) (T, error) {
+	delay := c.base
+
+	retryable := c.retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		out, err := fn()
+		if err == nil || attempt >= c.maxAttempts || !retryable(err) {
+			return out, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return out, err
+		case <-timer.C:
+		}
+		delay *= 2
+
+		if rewind != nil {
+			if rewindErr := rewind(); rewindErr != nil {
+				return out, err
+			}
+		}
+	}
+}
+
+// rewinder returns a function seeking body back to its current position,
+// or ok false if the body can not be read again.
+func rewinder(body io.Reader) (rewind func() error, ok bool) {
+	if body == nil {
+		return nil, true
+	}
+
+	seeker, isSeeker := body.(io.Seeker)
+	if !isSeeker {
+		return nil, false
+	}
+
+	pos, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false
+	}
+
+	return func() error {
+		_, err := seeker.Seek(pos, io.SeekStart)
+		return err
+	}, true
+}
+
+func (c *retryClient) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.HeadBucketOutput, error) { return c.client.HeadBucket(ctx, params, optFns...) })
+}
+
+func (c *retryClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.HeadObjectOutput, error) { return c.client.HeadObject(ctx, params, optFns...) })
+}
+
+func (c *retryClient) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.CopyObjectOutput, error) { return c.client.CopyObject(ctx, params, optFns...) })
+}
+
+func (c *retryClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	rewind, ok := rewinder(params.Body)
+	if !ok {
+		return c.client.PutObject(ctx, params, optFns...)
+	}
+	return retry(ctx, c, rewind, func() (*s3.PutObjectOutput, error) { return c.client.PutObject(ctx, params, optFns...) })
+}
+
+func (c *retryClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.GetObjectOutput, error) { return c.client.GetObject(ctx, params, optFns...) })
+}
+
+func (c *retryClient) GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.GetObjectAttributesOutput, error) {
+		return c.client.GetObjectAttributes(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.DeleteObjectOutput, error) { return c.client.DeleteObject(ctx, params, optFns...) })
+}
+
+func (c *retryClient) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.DeleteObjectsOutput, error) { return c.client.DeleteObjects(ctx, params, optFns...) })
+}
+
+func (c *retryClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return retry(ctx, c, nil, func() (*s3.ListObjectsV2Output, error) { return c.client.ListObjectsV2(ctx, params, optFns...) })
+}
+
+func (c *retryClient) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.ListObjectVersionsOutput, error) {
+		return c.client.ListObjectVersions(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	rewind, ok := rewinder(params.Body)
+	if !ok {
+		return c.client.UploadPart(ctx, params, optFns...)
+	}
+	return retry(ctx, c, rewind, func() (*s3.UploadPartOutput, error) { return c.client.UploadPart(ctx, params, optFns...) })
+}
+
+func (c *retryClient) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.UploadPartCopyOutput, error) { return c.client.UploadPartCopy(ctx, params, optFns...) })
+}
+
+func (c *retryClient) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.CreateMultipartUploadOutput, error) {
+		return c.client.CreateMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.CompleteMultipartUploadOutput, error) {
+		return c.client.CompleteMultipartUpload(ctx, params, optFns...)
+	})
+}
+
+func (c *retryClient) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return retry(ctx, c, nil, func() (*s3.AbortMultipartUploadOutput, error) {
+		return c.client.AbortMultipartUpload(ctx, params, optFns...)
+	})
+}
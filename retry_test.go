@@ -0,0 +1,238 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+// flakyClient fails the calls of ListObjectsV2, PutObject and DeleteObject listed in failures
+// with the error code, SlowDown by default.
+type flakyClient struct {
+	*s3fstest.Client
+	failures map[int64]bool
+	code     string
+	calls    atomic.Int64
+}
+
+func (c *flakyClient) fail(op string) error {
+	if !c.failures[c.calls.Add(1)] {
+		return nil
+	}
+	if c.code != "" {
+		return s3fstest.Error(op, http.StatusBadRequest, &smithy.GenericAPIError{Code: c.code, Message: "Backend busy."})
+	}
+	return s3fstest.Error(op, http.StatusServiceUnavailable, &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."})
+}
+
+func (c *flakyClient) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if err := c.fail("ListObjectsV2"); err != nil {
+		return nil, err
+	}
+	return c.Client.ListObjectsV2(ctx, in, optFns...)
+}
+
+func (c *flakyClient) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if err := c.fail("PutObject"); err != nil {
+		return nil, err
+	}
+	return c.Client.PutObject(ctx, in, optFns...)
+}
+
+func (c *flakyClient) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if err := c.fail("DeleteObject"); err != nil {
+		return nil, err
+	}
+	return c.Client.DeleteObject(ctx, in, optFns...)
+}
+
+func TestRetryReadDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		wantErr  bool
+	}{
+		{name: "recovers", attempts: 3},
+		{name: "exhausted", attempts: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := s3fstest.New()
+			for i := 0; i < 2500; i++ {
+				mock.Put("test", fmt.Sprintf("dir/file%04d", i), nil)
+			}
+
+			// the second page fails twice
+			client := &flakyClient{Client: mock, failures: map[int64]bool{3: true, 4: true}}
+			fsys := New(client, "test", WithRetry(tt.attempts, time.Millisecond))
+
+			entries, err := fsys.ReadDir("dir")
+			if tt.wantErr {
+				var apiErr smithy.APIError
+				if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "SlowDown" {
+					t.Errorf("ReadDir() error = %v, want SlowDown", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the current directory entry and the files
+			if len(entries) != 2501 {
+				t.Errorf("entries = %d, want 2501", len(entries))
+			}
+		})
+	}
+}
+
+func TestRetryUpload(t *testing.T) {
+	mock := s3fstest.New()
+	mock.CreateBucket("test")
+	client := &flakyClient{Client: mock, failures: map[int64]bool{2: true, 3: true}}
+	fsys := New(client, "test", WithRetry(3, time.Millisecond))
+
+	f, err := fsys.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if obj, _ := mock.Object("test", "file"); string(obj.Data) != "content" {
+		t.Errorf("content = %q, want %q", obj.Data, "content")
+	}
+}
+
+func TestRetryBaseClient(t *testing.T) {
+	client := s3fstest.New()
+	fsys := New(client, "test", WithRetry(3, time.Millisecond), WithMetrics(newFakeRecorder()))
+
+	if got := baseClient(fsys.client); got != client {
+		t.Errorf("baseClient() = %T, want %T", got, client)
+	}
+}
+
+func TestRenameRetry(t *testing.T) {
+	tests := []struct {
+		wantErr error
+		name    string
+		exists  []string
+	}{
+		{name: "recovers", exists: []string{"new"}},
+		{name: "exhausted", exists: []string{"old", "new"}, wantErr: ErrRenameCleanupFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := s3fstest.New()
+			mock.Put("test", "old", []byte("content"))
+
+			// the two stats list, then the delete fails twice
+			failures := map[int64]bool{3: true, 4: true}
+			if tt.wantErr != nil {
+				failures[5] = true
+			}
+			client := &flakyClient{Client: mock, failures: failures}
+			fsys := New(client, "test", WithRenameRetry(3, time.Millisecond))
+
+			if err := fsys.Rename("old", "new"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Rename() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := client.calls.Load(); got != 5 {
+				t.Errorf("calls = %d, want 5", got)
+			}
+
+			for _, name := range []string{"old", "new"} {
+				_, found := mock.Object("test", name)
+				if want := slices.Contains(tt.exists, name); found != want {
+					t.Errorf("object %q exists = %v, want %v", name, found, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenameRetryWithRetry(t *testing.T) {
+	mock := s3fstest.New()
+	mock.Put("test", "old", []byte("content"))
+
+	// the two stats list, then every delete fails
+	failures := map[int64]bool{}
+	for i := int64(3); i < 20; i++ {
+		failures[i] = true
+	}
+	client := &flakyClient{Client: mock, failures: failures}
+	fsys := New(client, "test", WithRetry(2, time.Millisecond), WithRenameRetry(3, time.Millisecond))
+
+	if err := fsys.Rename("old", "new"); !errors.Is(err, ErrRenameCleanupFailed) {
+		t.Errorf("Rename() error = %v, want %v", err, ErrRenameCleanupFailed)
+	}
+
+	// 3 deletes, not 3 times 2
+	if got := client.calls.Load(); got != 5 {
+		t.Errorf("calls = %d, want 5", got)
+	}
+}
+
+func TestRetryClassifier(t *testing.T) {
+	tests := []struct {
+		classifier func(error) bool
+		name       string
+		wantCalls  int64
+		wantErr    bool
+	}{
+		{name: "default", wantCalls: 1, wantErr: true},
+		{
+			name: "custom",
+			classifier: func(err error) bool {
+				var apiErr smithy.APIError
+				return IsRetryable(err) || errors.As(err, &apiErr) && apiErr.ErrorCode() == "BackendBusy"
+			},
+			wantCalls: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := s3fstest.New()
+			mock.Put("test", "old", []byte("content"))
+
+			// the first listing fails with an error not retried by default
+			client := &flakyClient{Client: mock, failures: map[int64]bool{1: true}, code: "BackendBusy"}
+
+			opts := []Option{WithRetry(3, time.Millisecond)}
+			if tt.classifier != nil {
+				opts = append(opts, WithRetryClassifier(tt.classifier))
+			}
+			fsys := New(client, "test", opts...)
+
+			if _, err := fsys.ReadDir("."); (err != nil) != tt.wantErr {
+				t.Errorf("ReadDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := client.calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,1029 @@
+// Package s3fstest provides an in-memory S3 client to test code using s3fs,
+// without an S3 endpoint:
+//
+//	client := s3fstest.New()
+//	client.CreateBucket("bucket")
+//	fsys := s3fs.New(client, "bucket")
+//
+// It records the calls made, see Calls and Count.
+package s3fstest
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// defaultMaxKeys is the page size used by ListObjectsV2 when none is requested.
+const defaultMaxKeys = 1000
+
+// Object is an object stored in the mock.
+type Object struct {
+	LastModified    time.Time
+	Metadata        map[string]string
+	ContentType     *string
+	ContentLanguage *string
+	ContentEncoding *string
+	// CacheControl and ContentDisposition are the other HTTP headers kept with the object.
+	CacheControl       *string
+	ContentDisposition *string
+	ETag               string
+	// VersionID is set on the objects of buckets with versioning enabled.
+	VersionID string
+	// Checksum is computed with ChecksumAlgorithm when set on the upload,
+	// suffixed with the number of parts for multipart uploads.
+	ChecksumAlgorithm types.ChecksumAlgorithm
+	Checksum          string
+	// ObjectLockMode, ObjectLockRetainUntilDate and ObjectLockLegalHoldStatus
+	// are the object lock settings given on PutObject.
+	ObjectLockMode            types.ObjectLockMode
+	ObjectLockRetainUntilDate *time.Time
+	ObjectLockLegalHoldStatus types.ObjectLockLegalHoldStatus
+	Data                      []byte
+	PartSizes                 []int64
+}
+
+// Call is a recorded API call.
+type Call struct {
+	Input any
+	Op    string
+}
+
+type upload struct {
+	input *s3.CreateMultipartUploadInput
+	parts map[int32][]byte
+	key   string
+}
+
+// Client is an in-memory S3 client.
+// Buckets must be created with CreateBucket before being used.
+type Client struct {
+	buckets map[string]map[string]*Object
+	// versions holds every version of the keys of the buckets with versioning enabled,
+	// from the oldest to the newest.
+	versions map[string]map[string][]*Object
+	uploads  map[string]*upload
+	// Now is the clock used to set objects LastModified.
+	Now func() time.Time
+	// Region is the region of the buckets, us-east-1 by default.
+	Region string
+	calls  []Call
+	mu     sync.Mutex
+	seq    int
+}
+
+// New creates an empty client.
+func New() *Client {
+	return &Client{
+		buckets:  make(map[string]map[string]*Object),
+		versions: make(map[string]map[string][]*Object),
+		uploads:  make(map[string]*upload),
+		Now:      time.Now,
+		Region:   "us-east-1",
+	}
+}
+
+// CreateBucket creates an empty bucket.
+func (c *Client) CreateBucket(bucket string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.createBucket(bucket)
+}
+
+// Put stores an object without recording a call.
+func (c *Client) Put(bucket, key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.createBucket(bucket)
+	c.store(bucket, key, &Object{
+		Data:         data,
+		ETag:         etag(data),
+		LastModified: c.Now(),
+	})
+}
+
+// EnableVersioning creates a bucket keeping every version of its objects.
+// Deleting an object removes its current version only, without a delete marker.
+func (c *Client) EnableVersioning(bucket string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.createBucket(bucket)
+	if _, found := c.versions[bucket]; !found {
+		c.versions[bucket] = make(map[string][]*Object)
+	}
+}
+
+// store sets the current version of a key, keeping the previous ones on versioned buckets.
+func (c *Client) store(bucket, key string, obj *Object) {
+	c.buckets[bucket][key] = obj
+
+	versions, found := c.versions[bucket]
+	if !found {
+		return
+	}
+
+	c.seq++
+	obj.VersionID = "v" + strconv.Itoa(c.seq)
+	versions[key] = append(versions[key], obj)
+}
+
+// object returns the current version of a key, or the given one when versionID is set.
+func (c *Client) object(bucket, key string, versionID *string) (*Object, bool) {
+	if versionID == nil {
+		obj, found := c.buckets[bucket][key]
+		return obj, found
+	}
+
+	for _, obj := range c.versions[bucket][key] {
+		if obj.VersionID == aws.ToString(versionID) {
+			return obj, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Client) createBucket(bucket string) {
+	if _, found := c.buckets[bucket]; !found {
+		c.buckets[bucket] = make(map[string]*Object)
+	}
+}
+
+// Object returns a copy of a stored object.
+func (c *Client) Object(bucket, key string) (Object, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, found := c.buckets[bucket][key]
+	if !found {
+		return Object{}, false
+	}
+
+	return *obj, true
+}
+
+// Keys returns the sorted keys stored in a bucket.
+func (c *Client) Keys(bucket string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return sortedKeys(c.buckets[bucket])
+}
+
+// Calls returns the recorded calls.
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Call(nil), c.calls...)
+}
+
+// Count returns the number of recorded calls of an operation.
+func (c *Client) Count(op string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, call := range c.calls {
+		if call.Op == op {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Reset clears the recorded calls.
+func (c *Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls = nil
+}
+
+func (c *Client) record(op string, input any) {
+	c.calls = append(c.calls, Call{Op: op, Input: input})
+}
+
+func (c *Client) bucket(op, name string) (map[string]*Object, error) {
+	b, found := c.buckets[name]
+	if !found {
+		return nil, Error(op, http.StatusNotFound, &types.NoSuchBucket{Message: aws.String("The specified bucket does not exist")})
+	}
+
+	return b, nil
+}
+
+// HeadBucket implements the S3 HeadBucket operation.
+func (c *Client) HeadBucket(_ context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("HeadBucket", params)
+
+	if _, err := c.bucket("HeadBucket", aws.ToString(params.Bucket)); err != nil {
+		// HEAD responses have no body, so the error code is lost.
+		return nil, Error("HeadBucket", http.StatusNotFound, &types.NotFound{})
+	}
+
+	return &s3.HeadBucketOutput{BucketRegion: aws.String(c.Region)}, nil
+}
+
+// HeadObject implements the S3 HeadObject operation.
+func (c *Client) HeadObject(_ context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("HeadObject", params)
+
+	if _, err := c.bucket("HeadObject", aws.ToString(params.Bucket)); err != nil {
+		// HEAD responses have no body, so the error code is lost.
+		return nil, Error("HeadObject", http.StatusNotFound, &types.NotFound{})
+	}
+
+	obj, found := c.object(aws.ToString(params.Bucket), aws.ToString(params.Key), params.VersionId)
+	if !found {
+		return nil, Error("HeadObject", http.StatusNotFound, &types.NotFound{})
+	}
+
+	out := &s3.HeadObjectOutput{
+		ContentLength:      aws.Int64(int64(len(obj.Data))),
+		ContentType:        obj.ContentType,
+		ContentLanguage:    obj.ContentLanguage,
+		CacheControl:       obj.CacheControl,
+		ContentDisposition: obj.ContentDisposition,
+		ContentEncoding:    obj.ContentEncoding,
+		ETag:               aws.String(obj.ETag),
+		LastModified:       aws.Time(obj.LastModified),
+		Metadata:           copyMetadata(obj.Metadata),
+		PartsCount:         partsCount(obj),
+		VersionId:          versionID(obj),
+
+		ObjectLockMode:            obj.ObjectLockMode,
+		ObjectLockRetainUntilDate: obj.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: obj.ObjectLockLegalHoldStatus,
+	}
+
+	if params.ChecksumMode == types.ChecksumModeEnabled {
+		if sum := objectChecksum(obj); sum != nil {
+			out.ChecksumCRC32 = sum.ChecksumCRC32
+			out.ChecksumCRC32C = sum.ChecksumCRC32C
+			out.ChecksumSHA1 = sum.ChecksumSHA1
+			out.ChecksumSHA256 = sum.ChecksumSHA256
+		}
+	}
+
+	return out, nil
+}
+
+// GetObject implements the S3 GetObject operation.
+func (c *Client) GetObject(_ context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("GetObject", params)
+
+	if _, err := c.bucket("GetObject", aws.ToString(params.Bucket)); err != nil {
+		return nil, err
+	}
+
+	obj, found := c.object(aws.ToString(params.Bucket), aws.ToString(params.Key), params.VersionId)
+	if !found {
+		if params.VersionId != nil {
+			return nil, Error("GetObject", http.StatusNotFound, &smithy.GenericAPIError{Code: "NoSuchVersion", Message: "The specified version does not exist."})
+		}
+		return nil, Error("GetObject", http.StatusNotFound, &types.NoSuchKey{Message: aws.String("The specified key does not exist.")})
+	}
+
+	if err := checkConditions("GetObject", obj, params.IfMatch, nil); err != nil {
+		return nil, err
+	}
+
+	size := int64(len(obj.Data))
+	out := &s3.GetObjectOutput{
+		ContentType:        obj.ContentType,
+		ContentLanguage:    obj.ContentLanguage,
+		CacheControl:       obj.CacheControl,
+		ContentDisposition: obj.ContentDisposition,
+		ContentEncoding:    obj.ContentEncoding,
+		ETag:               aws.String(obj.ETag),
+		LastModified:       aws.Time(obj.LastModified),
+		Metadata:           copyMetadata(obj.Metadata),
+		PartsCount:         partsCount(obj),
+		VersionId:          versionID(obj),
+	}
+
+	start, end := int64(0), size-1
+
+	switch {
+	case params.PartNumber != nil:
+		n := int(aws.ToInt32(params.PartNumber))
+		if len(obj.PartSizes) == 0 && n == 1 {
+			break
+		}
+		if n < 1 || n > len(obj.PartSizes) {
+			return nil, Error("GetObject", http.StatusRequestedRangeNotSatisfiable, &smithy.GenericAPIError{Code: "InvalidPartNumber", Message: "The requested partnumber is not satisfiable"})
+		}
+		for _, s := range obj.PartSizes[:n-1] {
+			start += s
+		}
+		end = start + obj.PartSizes[n-1] - 1
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+
+	case params.Range != nil && size > 0:
+		var ok bool
+		start, end, ok = parseRange(aws.ToString(params.Range), size)
+		if !ok {
+			return nil, Error("GetObject", http.StatusRequestedRangeNotSatisfiable, &smithy.GenericAPIError{Code: "InvalidRange", Message: "The requested range is not satisfiable"})
+		}
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+
+	data := obj.Data[start : end+1]
+	out.ContentLength = aws.Int64(int64(len(data)))
+	out.Body = io.NopCloser(bytes.NewReader(data))
+
+	return out, nil
+}
+
+// GetObjectAttributes implements the S3 GetObjectAttributes operation,
+// returning the size, ETag and checksum.
+func (c *Client) GetObjectAttributes(_ context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("GetObjectAttributes", params)
+
+	b, err := c.bucket("GetObjectAttributes", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	obj, found := b[aws.ToString(params.Key)]
+	if !found {
+		return nil, Error("GetObjectAttributes", http.StatusNotFound, &types.NoSuchKey{})
+	}
+
+	return &s3.GetObjectAttributesOutput{
+		Checksum:     objectChecksum(obj),
+		ETag:         aws.String(strings.Trim(obj.ETag, `"`)),
+		LastModified: aws.Time(obj.LastModified),
+		ObjectSize:   aws.Int64(int64(len(obj.Data))),
+	}, nil
+}
+
+// PutObject implements the S3 PutObject operation.
+func (c *Client) PutObject(_ context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("PutObject", params)
+
+	b, err := c.bucket("PutObject", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkConditions("PutObject", b[aws.ToString(params.Key)], params.IfMatch, params.IfNoneMatch); err != nil {
+		return nil, err
+	}
+
+	if params.ContentMD5 != nil {
+		sum := md5.Sum(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != aws.ToString(params.ContentMD5) {
+			return nil, Error("PutObject", http.StatusBadRequest, &smithy.GenericAPIError{Code: "BadDigest", Message: "The Content-MD5 you specified did not match what we received."})
+		}
+	}
+
+	obj := &Object{
+		Data:               data,
+		ETag:               etag(data),
+		LastModified:       c.Now(),
+		ContentType:        params.ContentType,
+		ContentLanguage:    params.ContentLanguage,
+		CacheControl:       params.CacheControl,
+		ContentDisposition: params.ContentDisposition,
+		ContentEncoding:    params.ContentEncoding,
+		Metadata:           copyMetadata(params.Metadata),
+
+		ObjectLockMode:            params.ObjectLockMode,
+		ObjectLockRetainUntilDate: params.ObjectLockRetainUntilDate,
+		ObjectLockLegalHoldStatus: params.ObjectLockLegalHoldStatus,
+	}
+	if params.ChecksumAlgorithm != "" {
+		obj.ChecksumAlgorithm = params.ChecksumAlgorithm
+		obj.Checksum = base64.StdEncoding.EncodeToString(checksum(params.ChecksumAlgorithm, data))
+	}
+	c.store(aws.ToString(params.Bucket), aws.ToString(params.Key), obj)
+
+	return &s3.PutObjectOutput{ETag: aws.String(obj.ETag), VersionId: versionID(obj)}, nil
+}
+
+// CopyObject implements the S3 CopyObject operation.
+func (c *Client) CopyObject(_ context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("CopyObject", params)
+
+	source, err := url.PathUnescape(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, Error("CopyObject", http.StatusBadRequest, &smithy.GenericAPIError{Code: "InvalidArgument", Message: err.Error()})
+	}
+
+	srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+
+	sb, err := c.bucket("CopyObject", srcBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	src, found := sb[srcKey]
+	if !found {
+		return nil, Error("CopyObject", http.StatusNotFound, &types.NoSuchKey{Message: aws.String("The specified key does not exist.")})
+	}
+
+	if _, err := c.bucket("CopyObject", aws.ToString(params.Bucket)); err != nil {
+		return nil, err
+	}
+
+	obj := &Object{
+		Data:               src.Data,
+		ETag:               src.ETag,
+		ChecksumAlgorithm:  src.ChecksumAlgorithm,
+		Checksum:           src.Checksum,
+		PartSizes:          src.PartSizes,
+		LastModified:       c.Now(),
+		ContentType:        src.ContentType,
+		ContentLanguage:    src.ContentLanguage,
+		CacheControl:       src.CacheControl,
+		ContentDisposition: src.ContentDisposition,
+		ContentEncoding:    src.ContentEncoding,
+		Metadata:           copyMetadata(src.Metadata),
+	}
+
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		obj.ContentType = params.ContentType
+		obj.ContentLanguage = params.ContentLanguage
+		obj.CacheControl = params.CacheControl
+		obj.ContentDisposition = params.ContentDisposition
+		obj.ContentEncoding = params.ContentEncoding
+		obj.Metadata = copyMetadata(params.Metadata)
+	}
+
+	c.store(aws.ToString(params.Bucket), aws.ToString(params.Key), obj)
+
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{
+			ETag:         aws.String(obj.ETag),
+			LastModified: aws.Time(obj.LastModified),
+		},
+	}, nil
+}
+
+// DeleteObject implements the S3 DeleteObject operation.
+func (c *Client) DeleteObject(_ context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("DeleteObject", params)
+
+	b, err := c.bucket("DeleteObject", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(b, aws.ToString(params.Key))
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// DeleteObjects implements the S3 DeleteObjects operation.
+func (c *Client) DeleteObjects(_ context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("DeleteObjects", params)
+
+	b, err := c.bucket("DeleteObjects", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Delete == nil || len(params.Delete.Objects) == 0 || len(params.Delete.Objects) > 1000 {
+		return nil, Error("DeleteObjects", http.StatusBadRequest, &smithy.GenericAPIError{Code: "MalformedXML", Message: "The XML you provided was not well-formed or did not validate against our published schema."})
+	}
+
+	out := &s3.DeleteObjectsOutput{}
+
+	for _, obj := range params.Delete.Objects {
+		delete(b, aws.ToString(obj.Key))
+
+		if !aws.ToBool(params.Delete.Quiet) {
+			out.Deleted = append(out.Deleted, types.DeletedObject{Key: obj.Key})
+		}
+	}
+
+	return out, nil
+}
+
+// ListObjectVersions implements the S3 ListObjectVersions operation, in a single page.
+// Unversioned buckets list their objects with the null version.
+func (c *Client) ListObjectVersions(_ context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("ListObjectVersions", params)
+
+	bucket := aws.ToString(params.Bucket)
+	b, err := c.bucket("ListObjectVersions", bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, versioned := c.versions[bucket]
+	if !versioned {
+		versions = make(map[string][]*Object, len(b))
+		for key, obj := range b {
+			versions[key] = []*Object{obj}
+		}
+	}
+
+	keys := make([]string, 0, len(versions))
+	for key := range versions {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := &s3.ListObjectVersionsOutput{
+		Name:        params.Bucket,
+		Prefix:      params.Prefix,
+		IsTruncated: aws.Bool(false),
+	}
+
+	for _, key := range keys {
+		// newest first
+		for i := len(versions[key]) - 1; i >= 0; i-- {
+			obj := versions[key][i]
+
+			id := "null"
+			if obj.VersionID != "" {
+				id = obj.VersionID
+			}
+
+			out.Versions = append(out.Versions, types.ObjectVersion{
+				Key:          aws.String(key),
+				VersionId:    aws.String(id),
+				IsLatest:     aws.Bool(b[key] == obj),
+				ETag:         aws.String(obj.ETag),
+				Size:         aws.Int64(int64(len(obj.Data))),
+				LastModified: aws.Time(obj.LastModified),
+			})
+		}
+	}
+
+	return out, nil
+}
+
+// ListObjectsV2 implements the S3 ListObjectsV2 operation.
+func (c *Client) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("ListObjectsV2", params)
+
+	b, err := c.bucket("ListObjectsV2", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := aws.ToString(params.Prefix)
+	delimiter := aws.ToString(params.Delimiter)
+
+	maxKeys := int32(defaultMaxKeys)
+	if params.MaxKeys != nil && *params.MaxKeys < maxKeys {
+		maxKeys = *params.MaxKeys
+	}
+
+	after := aws.ToString(params.StartAfter)
+	if params.ContinuationToken != nil {
+		after = aws.ToString(params.ContinuationToken)
+	}
+
+	out := &s3.ListObjectsV2Output{
+		Name:      params.Bucket,
+		Prefix:    params.Prefix,
+		Delimiter: params.Delimiter,
+		MaxKeys:   aws.Int32(maxKeys),
+	}
+
+	var last string
+	for _, key := range sortedKeys(b) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		entry := key
+		isPrefix := false
+
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				entry = key[:len(prefix)+i+len(delimiter)]
+				isPrefix = true
+			}
+		}
+
+		if entry <= after || (isPrefix && strings.HasPrefix(after, entry)) {
+			continue
+		}
+
+		if aws.ToInt32(out.KeyCount) >= maxKeys {
+			out.IsTruncated = aws.Bool(true)
+			out.NextContinuationToken = aws.String(last)
+			break
+		}
+
+		if isPrefix {
+			out.CommonPrefixes = append(out.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(entry)})
+			// skip every key rolled up into the common prefix
+			after = entry + string(rune(0x10FFFF))
+		} else {
+			obj := b[key]
+			out.Contents = append(out.Contents, types.Object{
+				Key:          aws.String(key),
+				Size:         aws.Int64(int64(len(obj.Data))),
+				ETag:         aws.String(obj.ETag),
+				LastModified: aws.Time(obj.LastModified),
+				StorageClass: types.ObjectStorageClassStandard,
+			})
+		}
+
+		last = entry
+		out.KeyCount = aws.Int32(aws.ToInt32(out.KeyCount) + 1)
+	}
+
+	if out.KeyCount == nil {
+		out.KeyCount = aws.Int32(0)
+	}
+
+	if out.IsTruncated == nil {
+		out.IsTruncated = aws.Bool(false)
+	}
+
+	return out, nil
+}
+
+// CreateMultipartUpload implements the S3 CreateMultipartUpload operation.
+func (c *Client) CreateMultipartUpload(_ context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("CreateMultipartUpload", params)
+
+	if _, err := c.bucket("CreateMultipartUpload", aws.ToString(params.Bucket)); err != nil {
+		return nil, err
+	}
+
+	c.seq++
+	id := strconv.Itoa(c.seq)
+	c.uploads[id] = &upload{
+		input: params,
+		key:   aws.ToString(params.Key),
+		parts: make(map[int32][]byte),
+	}
+
+	return &s3.CreateMultipartUploadOutput{
+		Bucket:   params.Bucket,
+		Key:      params.Key,
+		UploadId: aws.String(id),
+	}, nil
+}
+
+// UploadPart implements the S3 UploadPart operation.
+func (c *Client) UploadPart(_ context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("UploadPart", params)
+
+	u, found := c.uploads[aws.ToString(params.UploadId)]
+	if !found {
+		return nil, Error("UploadPart", http.StatusNotFound, &types.NoSuchUpload{})
+	}
+
+	u.parts[aws.ToInt32(params.PartNumber)] = data
+
+	return &s3.UploadPartOutput{ETag: aws.String(etag(data))}, nil
+}
+
+// UploadPartCopy implements the S3 UploadPartCopy operation.
+func (c *Client) UploadPartCopy(_ context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("UploadPartCopy", params)
+
+	u, found := c.uploads[aws.ToString(params.UploadId)]
+	if !found {
+		return nil, Error("UploadPartCopy", http.StatusNotFound, &types.NoSuchUpload{})
+	}
+
+	source, err := url.PathUnescape(aws.ToString(params.CopySource))
+	if err != nil {
+		return nil, Error("UploadPartCopy", http.StatusBadRequest, &smithy.GenericAPIError{Code: "InvalidArgument", Message: err.Error()})
+	}
+
+	srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+
+	sb, err := c.bucket("UploadPartCopy", srcBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	src, found := sb[srcKey]
+	if !found {
+		return nil, Error("UploadPartCopy", http.StatusNotFound, &types.NoSuchKey{Message: aws.String("The specified key does not exist.")})
+	}
+
+	data := src.Data
+	if params.CopySourceRange != nil {
+		start, end, ok := parseRange(aws.ToString(params.CopySourceRange), int64(len(src.Data)))
+		if !ok {
+			return nil, Error("UploadPartCopy", http.StatusRequestedRangeNotSatisfiable, &smithy.GenericAPIError{Code: "InvalidRange", Message: "The requested range is not satisfiable"})
+		}
+		data = src.Data[start : end+1]
+	}
+
+	u.parts[aws.ToInt32(params.PartNumber)] = bytes.Clone(data)
+
+	return &s3.UploadPartCopyOutput{
+		CopyPartResult: &types.CopyPartResult{
+			ETag:         aws.String(etag(data)),
+			LastModified: aws.Time(c.Now()),
+		},
+	}, nil
+}
+
+// CompleteMultipartUpload implements the S3 CompleteMultipartUpload operation.
+func (c *Client) CompleteMultipartUpload(_ context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("CompleteMultipartUpload", params)
+
+	u, found := c.uploads[aws.ToString(params.UploadId)]
+	if !found {
+		return nil, Error("CompleteMultipartUpload", http.StatusNotFound, &types.NoSuchUpload{})
+	}
+
+	b, err := c.bucket("CompleteMultipartUpload", aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkConditions("CompleteMultipartUpload", b[u.key], params.IfMatch, params.IfNoneMatch); err != nil {
+		return nil, err
+	}
+
+	var (
+		data      []byte
+		sizes     []int64
+		sums      []byte
+		checksums []byte
+	)
+
+	alg := u.input.ChecksumAlgorithm
+
+	if params.MultipartUpload != nil {
+		for _, p := range params.MultipartUpload.Parts {
+			part, ok := u.parts[aws.ToInt32(p.PartNumber)]
+			if !ok {
+				return nil, Error("CompleteMultipartUpload", http.StatusBadRequest, &smithy.GenericAPIError{Code: "InvalidPart"})
+			}
+			sum := md5.Sum(part)
+			sums = append(sums, sum[:]...)
+			if alg != "" {
+				checksums = append(checksums, checksum(alg, part)...)
+			}
+			data = append(data, part...)
+			sizes = append(sizes, int64(len(part)))
+		}
+	}
+
+	sum := md5.Sum(sums)
+	obj := &Object{
+		Data:               data,
+		PartSizes:          sizes,
+		ETag:               fmt.Sprintf("%q", fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(sizes))),
+		LastModified:       c.Now(),
+		ContentType:        u.input.ContentType,
+		ContentLanguage:    u.input.ContentLanguage,
+		CacheControl:       u.input.CacheControl,
+		ContentDisposition: u.input.ContentDisposition,
+		ContentEncoding:    u.input.ContentEncoding,
+		Metadata:           copyMetadata(u.input.Metadata),
+	}
+	if alg != "" {
+		obj.ChecksumAlgorithm = alg
+		obj.Checksum = fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(checksum(alg, checksums)), len(sizes))
+	}
+	c.store(aws.ToString(params.Bucket), u.key, obj)
+	delete(c.uploads, aws.ToString(params.UploadId))
+
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket: params.Bucket,
+		Key:    params.Key,
+		ETag:   aws.String(obj.ETag),
+	}, nil
+}
+
+// AbortMultipartUpload implements the S3 AbortMultipartUpload operation.
+func (c *Client) AbortMultipartUpload(_ context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.record("AbortMultipartUpload", params)
+
+	delete(c.uploads, aws.ToString(params.UploadId))
+
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// versionID returns the VersionId of an object, nil when unversioned.
+func versionID(obj *Object) *string {
+	if obj.VersionID == "" {
+		return nil
+	}
+	return aws.String(obj.VersionID)
+}
+
+// Error builds an error shaped like the ones returned by the SDK.
+func Error(op string, statusCode int, err error) error {
+	return &smithy.OperationError{
+		ServiceID:     "S3",
+		OperationName: op,
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: statusCode, Header: http.Header{}}},
+				Err:      err,
+			},
+		},
+	}
+}
+
+// checkConditions evaluates the If-Match and If-None-Match write conditions against the current object.
+func checkConditions(op string, obj *Object, ifMatch, ifNoneMatch *string) error {
+	failed := &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+
+	if ifNoneMatch != nil && obj != nil {
+		return Error(op, http.StatusPreconditionFailed, failed)
+	}
+
+	if ifMatch != nil && (obj == nil || obj.ETag != aws.ToString(ifMatch)) {
+		if obj == nil {
+			return Error(op, http.StatusNotFound, &smithy.GenericAPIError{Code: "NoSuchKey"})
+		}
+		return Error(op, http.StatusPreconditionFailed, failed)
+	}
+
+	return nil
+}
+
+func parseRange(s string, size int64) (int64, int64, bool) {
+	spec, found := strings.CutPrefix(s, "bytes=")
+	if !found {
+		return 0, 0, false
+	}
+
+	first, last, _ := strings.Cut(spec, "-")
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+	}
+
+	return start, min(end, size-1), true
+}
+
+func partsCount(obj *Object) *int32 {
+	if len(obj.PartSizes) == 0 {
+		return nil
+	}
+	return aws.Int32(int32(len(obj.PartSizes)))
+}
+
+func sortedKeys(objects map[string]*Object) []string {
+	keys := make([]string, 0, len(objects))
+	for k := range objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+
+	return result
+}
+
+// checksum returns the raw checksum of data with the algorithm.
+func checksum(alg types.ChecksumAlgorithm, data []byte) []byte {
+	var h hash.Hash
+
+	switch alg {
+	case types.ChecksumAlgorithmCrc32:
+		h = crc32.NewIEEE()
+	case types.ChecksumAlgorithmCrc32c:
+		h = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case types.ChecksumAlgorithmSha1:
+		h = sha1.New()
+	default:
+		h = sha256.New()
+	}
+
+	h.Write(data)
+
+	return h.Sum(nil)
+}
+
+// objectChecksum returns the checksum of obj, nil if it has none.
+func objectChecksum(obj *Object) *types.Checksum {
+	if obj.Checksum == "" {
+		return nil
+	}
+
+	sum := &types.Checksum{}
+	switch obj.ChecksumAlgorithm {
+	case types.ChecksumAlgorithmCrc32:
+		sum.ChecksumCRC32 = aws.String(obj.Checksum)
+	case types.ChecksumAlgorithmCrc32c:
+		sum.ChecksumCRC32C = aws.String(obj.Checksum)
+	case types.ChecksumAlgorithmSha1:
+		sum.ChecksumSHA1 = aws.String(obj.Checksum)
+	default:
+		sum.ChecksumSHA256 = aws.String(obj.Checksum)
+	}
+
+	return sum
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return fmt.Sprintf("%q", hex.EncodeToString(sum[:]))
+}
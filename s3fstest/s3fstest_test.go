@@ -0,0 +1,173 @@
+package s3fstest_test
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/jacoelho/s3fs"
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func newClient(keys ...string) *s3fstest.Client {
+	client := s3fstest.New()
+	client.CreateBucket("bucket")
+	for _, key := range keys {
+		client.Put("bucket", key, []byte(key))
+	}
+	return client
+}
+
+// listAll lists the prefix a page of maxKeys at a time,
+// returning each page as its keys followed by its common prefixes.
+func listAll(t *testing.T, client *s3fstest.Client, prefix, delimiter string, maxKeys int32) []string {
+	t.Helper()
+
+	in := &s3.ListObjectsV2Input{
+		Bucket:  aws.String("bucket"),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int32(maxKeys),
+	}
+	if delimiter != "" {
+		in.Delimiter = aws.String(delimiter)
+	}
+
+	var pages []string
+
+	for {
+		out, err := client.ListObjectsV2(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var page []string
+		for _, obj := range out.Contents {
+			page = append(page, aws.ToString(obj.Key))
+		}
+		for _, p := range out.CommonPrefixes {
+			page = append(page, aws.ToString(p.Prefix))
+		}
+
+		if got := int(aws.ToInt32(out.KeyCount)); got != len(page) {
+			t.Errorf("KeyCount = %d, want %d", got, len(page))
+		}
+		pages = append(pages, fmt.Sprint(page))
+
+		if !aws.ToBool(out.IsTruncated) {
+			if out.NextContinuationToken != nil {
+				t.Errorf("NextContinuationToken = %q on the last page", aws.ToString(out.NextContinuationToken))
+			}
+			return pages
+		}
+
+		in.ContinuationToken = out.NextContinuationToken
+	}
+}
+
+func TestListObjectsV2(t *testing.T) {
+	client := newClient(
+		"a.txt",
+		"b/1.txt",
+		"b/2.txt",
+		"b/c/3.txt",
+		"d.txt",
+		"e/4.txt",
+		"f.txt",
+	)
+
+	tests := []struct {
+		name      string
+		prefix    string
+		delimiter string
+		want      []string
+		maxKeys   int32
+	}{
+		{
+			name:    "flat",
+			maxKeys: 1000,
+			want:    []string{"[a.txt b/1.txt b/2.txt b/c/3.txt d.txt e/4.txt f.txt]"},
+		},
+		{
+			name:    "flat pages",
+			maxKeys: 3,
+			want:    []string{"[a.txt b/1.txt b/2.txt]", "[b/c/3.txt d.txt e/4.txt]", "[f.txt]"},
+		},
+		{
+			name:      "delimiter",
+			delimiter: "/",
+			maxKeys:   1000,
+			want:      []string{"[a.txt d.txt f.txt b/ e/]"},
+		},
+		{
+			// a common prefix counts as one key and is not repeated on the next page
+			name:      "delimiter pages",
+			delimiter: "/",
+			maxKeys:   2,
+			want:      []string{"[a.txt b/]", "[d.txt e/]", "[f.txt]"},
+		},
+		{
+			name:      "prefix and delimiter",
+			prefix:    "b/",
+			delimiter: "/",
+			maxKeys:   1,
+			want:      []string{"[b/1.txt]", "[b/2.txt]", "[b/c/]"},
+		},
+		{
+			name:    "no match",
+			prefix:  "z",
+			maxKeys: 1000,
+			want:    []string{"[]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := listAll(t, client, tt.prefix, tt.delimiter, tt.maxKeys)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFs(t *testing.T) {
+	client := newClient("dir/a.txt", "dir/sub/b.txt")
+	fsys := s3fs.New(client, "bucket")
+
+	f, err := fsys.Create("dir/c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := fs.ReadDir(fsys, "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+
+	if want := "[. a.txt c.txt sub]"; fmt.Sprint(got) != want {
+		t.Errorf("ReadDir() = %v, want %v", got, want)
+	}
+
+	if err := fsys.Remove("dir/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := client.Object("bucket", "dir/a.txt"); found {
+		t.Error("dir/a.txt not removed")
+	}
+}
@@ -0,0 +1,148 @@
+package s3http
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jacoelho/s3fs"
+)
+
+// Entry is a directory entry of the JSON listings of DirectoryHandler.
+type Entry struct {
+	ModTime time.Time `json:"modTime"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"isDir"`
+}
+
+var listingTemplate = template.Must(template.New("listing").Funcs(template.FuncMap{"href": href}).Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Path}}</title></head>
+<body>
+<h1>{{.Path}}</h1>
+<ul>
+{{range .Entries}}<li><a href="{{href .}}">{{.Name}}{{if .IsDir}}/{{end}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// href returns the link of the entry, relative to its directory.
+func href(e Entry) string {
+	link := (&url.URL{Path: e.Name}).String()
+	if e.IsDir {
+		link += "/"
+	}
+	return link
+}
+
+// DirectoryHandler returns a handler browsing fsys.
+// Directories are listed as a JSON array of Entry, or as an HTML page when the Accept header asks for text/html.
+// As http.FileServer, directories are redirected to their path with a trailing slash,
+// which the relative links of the listings expect.
+// Files are served with http.ServeContent, supporting Range requests.
+func DirectoryHandler(fsys *s3fs.Fs) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Trim(path.Clean("/"+r.URL.Path), "/")
+		ctx := r.Context()
+
+		info, err := fsys.StatWithContext(ctx, name)
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+
+		if info.IsDir() {
+			if !strings.HasSuffix(r.URL.Path, "/") {
+				redirect(w, r, path.Base(r.URL.Path)+"/")
+				return
+			}
+			serveDirectory(w, r, fsys, name)
+			return
+		}
+
+		f, err := fsys.OpenWithContext(ctx, name)
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+		defer func() { _ = f.Close() }()
+
+		rs, ok := f.(io.ReadSeeker)
+		if !ok {
+			http.Error(w, "file is not seekable", http.StatusInternalServerError)
+			return
+		}
+
+		http.ServeContent(w, r, path.Base(name), info.ModTime(), rs)
+	})
+}
+
+func serveDirectory(w http.ResponseWriter, r *http.Request, fsys *s3fs.Fs, name string) {
+	entries, err := fsys.ReadDirWithContext(r.Context(), name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	listing := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Name() == "." {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+
+		listing = append(listing, Entry{
+			Name:    entry.Name(),
+			Size:    info.Size(),
+			IsDir:   entry.IsDir(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = listingTemplate.Execute(w, struct {
+			Path    string
+			Entries []Entry
+		}{Path: "/" + name, Entries: listing})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(listing)
+}
+
+// redirect replies with a redirect to the relative target, keeping the query, as http.FileServer does.
+func redirect(w http.ResponseWriter, r *http.Request, target string) {
+	if q := r.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// serveError replies with the HTTP status of err.
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package s3http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jacoelho/s3fs"
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func newTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	mock := s3fstest.New()
+	mock.Put("test", "dir/file.txt", []byte("0123456789"))
+	mock.Put("test", "dir/sub/nested.txt", []byte("nested"))
+	mock.Put("test", "dir/a#b?c%d.txt", nil)
+
+	return DirectoryHandler(s3fs.New(mock, "test"))
+}
+
+func TestDirectoryHandlerJSON(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dir/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var entries []Entry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v, want 3", entries)
+	}
+
+	if e := entries[1]; e.Name != "file.txt" || e.Size != 10 || e.IsDir {
+		t.Errorf("entries[1] = %+v, want file.txt of 10 bytes", e)
+	}
+
+	if e := entries[2]; e.Name != "sub" || !e.IsDir {
+		t.Errorf("entries[2] = %+v, want directory sub", e)
+	}
+}
+
+func TestDirectoryHandlerHTML(t *testing.T) {
+	handler := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/dir/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	for _, want := range []string{
+		`<a href="file.txt">file.txt</a>`,
+		`<a href="sub/">sub/</a>`,
+		`<a href="a%23b%3Fc%25d.txt">a#b?c%d.txt</a>`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("body = %s, want %s", rec.Body.String(), want)
+		}
+	}
+}
+
+func TestDirectoryHandlerRedirect(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dir/sub?x=1", nil))
+
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMovedPermanently)
+	}
+
+	if got := rec.Header().Get("Location"); got != "sub/?x=1" {
+		t.Errorf("Location = %q, want %q", got, "sub/?x=1")
+	}
+}
+
+func TestDirectoryHandlerFile(t *testing.T) {
+	handler := newTestHandler(t)
+
+	tests := []struct {
+		name       string
+		rangeH     string
+		wantBody   string
+		wantStatus int
+	}{
+		{name: "whole", wantStatus: http.StatusOK, wantBody: "0123456789"},
+		{name: "range", rangeH: "bytes=2-5", wantStatus: http.StatusPartialContent, wantBody: "2345"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/dir/file.txt", nil)
+			if tt.rangeH != "" {
+				req.Header.Set("Range", tt.rangeH)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Errorf("response = (%d, %q), want (%d, %q)", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestDirectoryHandlerNotFound(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.txt", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
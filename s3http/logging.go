@@ -0,0 +1,83 @@
+package s3http
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// clfTime is the timestamp layout of the Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// LoggingHandler returns a handler writing a line to w for each request served by h,
+// in Common Log Format followed by the duration in microseconds:
+//
+//	127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /dir/file HTTP/1.1" 200 2326 1520
+//
+// The user is the one of the basic authentication, if any. Writes to w are serialized.
+func LoggingHandler(h http.Handler, w io.Writer) http.Handler {
+	var mu sync.Mutex
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &loggingResponseWriter{ResponseWriter: rw, status: http.StatusOK}
+
+		h.ServeHTTP(lw, r)
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		user := "-"
+		if name, _, ok := r.BasicAuth(); ok && name != "" {
+			user = name
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		_, _ = fmt.Fprintf(w, "%s - %s [%s] %q %d %d %d\n",
+			host,
+			user,
+			start.Format(clfTime),
+			r.Method+" "+r.URL.RequestURI()+" "+r.Proto,
+			lw.status,
+			lw.written,
+			time.Since(start).Microseconds(),
+		)
+	})
+}
+
+// loggingResponseWriter records the status and the bytes of a response.
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	written int64
+	status  int
+}
+
+func (w *loggingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the io.ReaderFrom of the wrapped writer, used by http.ServeContent.
+func (w *loggingResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.written += n
+	return n, err
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
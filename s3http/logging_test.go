@@ -0,0 +1,62 @@
+package s3http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/jacoelho/s3fs"
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func TestLoggingHandler(t *testing.T) {
+	mock := s3fstest.New()
+	mock.Put("test", "dir/file.txt", bytes.Repeat([]byte("a"), 1234))
+
+	var logs bytes.Buffer
+	handler := LoggingHandler(http.FileServer(HTTPFileSystem(s3fs.New(mock, "test"))), &logs)
+
+	tests := []struct {
+		name    string
+		path    string
+		rangeH  string
+		wantLog string
+	}{
+		{
+			name:    "object",
+			path:    "/dir/file.txt",
+			wantLog: `^192\.0\.2\.1 - - \[[^\]]+\] "GET /dir/file.txt HTTP/1.1" 200 1234 \d+\n$`,
+		},
+		{
+			name:    "range",
+			path:    "/dir/file.txt",
+			rangeH:  "bytes=0-99",
+			wantLog: `^192\.0\.2\.1 - - \[[^\]]+\] "GET /dir/file.txt HTTP/1.1" 206 100 \d+\n$`,
+		},
+		{
+			name:    "missing",
+			path:    "/missing.txt",
+			wantLog: `^192\.0\.2\.1 - - \[[^\]]+\] "GET /missing.txt HTTP/1.1" 404 \d+ \d+\n$`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.rangeH != "" {
+				req.Header.Set("Range", tt.rangeH)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if !regexp.MustCompile(tt.wantLog).MatchString(logs.String()) {
+				t.Errorf("log = %q, want match %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
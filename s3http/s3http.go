@@ -0,0 +1,16 @@
+// Package s3http serves a s3fs.Fs over HTTP.
+package s3http
+
+import (
+	"net/http"
+
+	"github.com/jacoelho/s3fs"
+)
+
+// HTTPFileSystem returns the http.FileSystem of fsys, to be used with http.FileServer.
+// Files are seekable, so a Range request starts the download at the first byte served,
+// instead of at the start of the object. The download still runs to the end of the object,
+// unless the request completes and the file is closed first.
+func HTTPFileSystem(fsys *s3fs.Fs) http.FileSystem {
+	return http.FS(fsys)
+}
@@ -0,0 +1,221 @@
+// Package s3sftp serves a s3fs.Fs over SFTP.
+package s3sftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/jacoelho/s3fs"
+	"github.com/pkg/sftp"
+)
+
+var (
+	_ sftp.FileReader = (*handler)(nil)
+	_ sftp.FileWriter = (*handler)(nil)
+	_ sftp.FileCmder  = (*handler)(nil)
+	_ sftp.FileLister = (*handler)(nil)
+)
+
+type handler struct {
+	fsys *s3fs.Fs
+}
+
+// SFTPHandlers returns the sftp.Handlers serving fsys,
+// to be used with sftp.NewRequestServer.
+func SFTPHandlers(fsys *s3fs.Fs) sftp.Handlers {
+	h := &handler{fsys: fsys}
+
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// Fileread opens the named file for reading,
+// the file is closed by the server once the transfer completes.
+func (h *handler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	f, err := h.fsys.OpenWithContext(r.Context(), r.Filepath)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	file, ok := f.(*s3fs.File)
+	if !ok {
+		_ = f.Close()
+		return nil, &fs.PathError{Op: "open", Path: r.Filepath, Err: s3fs.ErrIsDirectory}
+	}
+
+	return file, nil
+}
+
+// Filewrite opens the named file for writing,
+// the upload completes when the server closes the file.
+func (h *handler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	if r.Pflags().Append {
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+
+	f, err := h.fsys.CreateWithContext(r.Context(), r.Filepath)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return f, nil
+}
+
+// Filecmd handles the commands that change the filesystem.
+func (h *handler) Filecmd(r *sftp.Request) error {
+	return statusError(h.filecmd(r))
+}
+
+func (h *handler) filecmd(r *sftp.Request) error {
+	ctx := r.Context()
+
+	switch r.Method {
+	case "Setstat":
+		return h.setstat(r)
+
+	case "Rename":
+		return h.fsys.RenameWithContext(ctx, r.Filepath, r.Target)
+
+	case "Rmdir":
+		return h.fsys.RemoveDirWithContext(ctx, r.Filepath)
+
+	case "Remove":
+		return h.fsys.RemoveWithContext(ctx, r.Filepath)
+
+	case "Mkdir":
+		_, err := h.fsys.CreateDirWithContext(ctx, r.Filepath)
+		return err
+	}
+
+	return sftp.ErrSSHFxOpUnsupported
+}
+
+// setstat applies the size attribute, the remaining ones are accepted and ignored:
+// S3 has no concept of permissions or ownership and the modification time is set by S3.
+func (h *handler) setstat(r *sftp.Request) error {
+	if !r.AttrFlags().Size {
+		return nil
+	}
+
+	ctx := r.Context()
+	size := int64(r.Attributes().Size)
+
+	info, err := h.fsys.StatWithContext(ctx, r.Filepath)
+	if err != nil {
+		return err
+	}
+
+	// clients usually set the size of the file they just uploaded
+	if info.Size() == size {
+		return nil
+	}
+
+	// growing is not supported, checked before replacing the object
+	if info.IsDir() || size > info.Size() {
+		return sftp.ErrSSHFxOpUnsupported
+	}
+
+	f, err := h.fsys.CreateWithContext(ctx, r.Filepath)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Filelist handles the listing and stat requests.
+func (h *handler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	l, err := h.filelist(r)
+	return l, statusError(err)
+}
+
+func (h *handler) filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case "List":
+		info, err := h.fsys.StatWithContext(ctx, r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: r.Filepath, Err: s3fs.ErrNotDirectory}
+		}
+
+		var entries listerAt
+
+		err = h.fsys.RangeDir(ctx, r.Filepath, func(entry fs.DirEntry) error {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			entries = append(entries, info)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return entries, nil
+
+	case "Stat":
+		info, err := h.fsys.StatWithContext(ctx, r.Filepath)
+		if err != nil {
+			return nil, err
+		}
+
+		return listerAt{&info}, nil
+	}
+
+	// Readlink included, S3 has no symbolic links
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// statusError translates the fs errors into sftp status codes,
+// as the server only recognizes unwrapped not exist errors.
+// Errors without a matching code are reported as SSH_FX_FAILURE.
+func statusError(err error) error {
+	var code error
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		code = sftp.ErrSSHFxNoSuchFile
+	case errors.Is(err, fs.ErrPermission):
+		code = sftp.ErrSSHFxPermissionDenied
+	default:
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", code, err)
+}
+
+type listerAt []os.FileInfo
+
+// ListAt implements sftp.ListerAt interface.
+func (l listerAt) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
@@ -0,0 +1,418 @@
+package s3sftp
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jacoelho/s3fs"
+	"github.com/jacoelho/s3fs/s3fstest"
+	"github.com/pkg/sftp"
+)
+
+func newTestClient(t *testing.T) (*sftp.Client, *s3fstest.Client) {
+	t.Helper()
+
+	mock := s3fstest.New()
+	mock.CreateBucket("test")
+
+	serverConn, clientConn := net.Pipe()
+
+	server := sftp.NewRequestServer(serverConn, SFTPHandlers(s3fs.New(mock, "test")))
+	go func() { _ = server.Serve() }()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	return client, mock
+}
+
+func assertKeys(t *testing.T, mock *s3fstest.Client, want ...string) {
+	t.Helper()
+
+	got := mock.Keys("test")
+	sort.Strings(want)
+
+	if len(got) != len(want) {
+		t.Fatalf("keys = %v, want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("keys = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPut(t *testing.T) {
+	client, mock := newTestClient(t)
+
+	f, err := client.Create("/dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, found := mock.Object("test", "dir/file")
+	if !found {
+		t.Fatal("object not found")
+	}
+
+	if got := string(obj.Data); got != "content" {
+		t.Fatalf("content = %q, want %q", got, "content")
+	}
+}
+
+func TestGet(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "file", []byte("content"))
+
+	f, err := client.Open("/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "content" {
+		t.Fatalf("content = %q, want %q", got, "content")
+	}
+}
+
+func TestGetNotExist(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	if _, err := client.Open("/file"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestMkdir(t *testing.T) {
+	client, mock := newTestClient(t)
+
+	if err := client.Mkdir("/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertKeys(t, mock, "dir/.keep")
+}
+
+func TestRmdir(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "dir/.keep", nil)
+
+	if err := client.RemoveDirectory("/dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertKeys(t, mock)
+}
+
+func TestRemove(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "file", []byte("content"))
+	mock.Put("test", "other", []byte("content"))
+
+	if err := client.Remove("/file"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertKeys(t, mock, "other")
+}
+
+func TestRename(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "file", []byte("content"))
+
+	if err := client.Rename("/file", "/dir/renamed"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertKeys(t, mock, "dir/renamed")
+}
+
+func TestSetstat(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "file", []byte("content"))
+
+	if err := client.Chmod("/file", 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	assertKeys(t, mock, "file")
+}
+
+func TestList(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "dir/a", []byte("a"))
+	mock.Put("test", "dir/b", []byte("bb"))
+	mock.Put("test", "dir/sub/c", []byte("c"))
+
+	entries, err := client.ReadDir("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+
+	want := []string{"a", "b", "sub"}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("entries = %v, want %v", got, want)
+		}
+	}
+
+	if !entries[2].IsDir() {
+		t.Errorf("%s should be a directory", entries[2].Name())
+	}
+}
+
+func TestListNotExist(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	if _, err := client.ReadDir("/dir"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("ReadDir() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestStat(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "dir/file", []byte("content"))
+
+	info, err := client.Stat("/dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() != int64(len("content")) || info.IsDir() {
+		t.Fatalf("Stat() = size %d, dir %v", info.Size(), info.IsDir())
+	}
+
+	info, err = client.Stat("/dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !info.IsDir() {
+		t.Fatal("dir should be a directory")
+	}
+}
+
+func TestReadlink(t *testing.T) {
+	client, mock := newTestClient(t)
+	mock.Put("test", "file", []byte("content"))
+
+	var statusErr *sftp.StatusError
+	if _, err := client.ReadLink("/file"); !errors.As(err, &statusErr) || statusErr.FxCode() != sftp.ErrSSHFxOpUnsupported {
+		t.Fatalf("ReadLink() error = %v, want %v", err, sftp.ErrSSHFxOpUnsupported)
+	}
+}
+
+func TestSetstatRsyncSequence(t *testing.T) {
+	client, mock := newTestClient(t)
+
+	f, err := client.Create("/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("content")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := client.Truncate("/file", int64(len("content"))); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+
+	if err := client.Chtimes("/file", modTime, modTime); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	if err := client.Chmod("/file", 0o644); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+
+	if err := client.Chown("/file", 1000, 1000); err != nil {
+		t.Fatalf("Chown() error = %v", err)
+	}
+
+	obj, found := mock.Object("test", "file")
+	if !found {
+		t.Fatal("object not found")
+	}
+
+	if got := string(obj.Data); got != "content" {
+		t.Fatalf("content = %q, want %q", got, "content")
+	}
+
+	if got := mock.Count("PutObject"); got != 1 {
+		t.Fatalf("PutObject calls = %d, want 1", got)
+	}
+}
+
+func TestSetstatTruncate(t *testing.T) {
+	tests := []struct {
+		wantErr error
+		name    string
+		want    string
+		size    int64
+	}{
+		{
+			name: "shrink",
+			size: 4,
+			want: "cont",
+		},
+		{
+			name: "empty",
+			size: 0,
+			want: "",
+		},
+		{
+			name:    "grow",
+			size:    100,
+			want:    "content",
+			wantErr: sftp.ErrSSHFxOpUnsupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := newTestClient(t)
+			mock.Put("test", "file", []byte("content"))
+
+			err := client.Truncate("/file", tt.size)
+			if tt.wantErr != nil {
+				var statusErr *sftp.StatusError
+				if !errors.As(err, &statusErr) || statusErr.FxCode() != tt.wantErr {
+					t.Fatalf("Truncate() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Truncate() error = %v", err)
+			}
+
+			obj, found := mock.Object("test", "file")
+			if !found {
+				t.Fatal("object not found")
+			}
+
+			if got := string(obj.Data); got != tt.want {
+				t.Fatalf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilecmdErrors(t *testing.T) {
+	tests := []struct {
+		cmd      func(*sftp.Client) error
+		name     string
+		wantKeys []string
+		wantCode fxCode
+	}{
+		{
+			name:     "rename missing file",
+			cmd:      func(c *sftp.Client) error { return c.Rename("/missing", "/renamed") },
+			wantKeys: []string{"dir/file"},
+			wantCode: codeNoSuchFile,
+		},
+		{
+			name:     "rename directory",
+			cmd:      func(c *sftp.Client) error { return c.Rename("/dir", "/renamed") },
+			wantKeys: []string{"dir/file"},
+			wantCode: codeFailure,
+		},
+		{
+			name:     "rmdir not empty",
+			cmd:      func(c *sftp.Client) error { return c.RemoveDirectory("/dir") },
+			wantKeys: []string{"dir/file"},
+			wantCode: codeFailure,
+		},
+		{
+			name:     "mkdir existing file",
+			cmd:      func(c *sftp.Client) error { return c.Mkdir("/dir/file") },
+			wantKeys: []string{"dir/file"},
+			wantCode: codeFailure,
+		},
+		{
+			name:     "stat missing file",
+			cmd:      func(c *sftp.Client) error { _, err := c.Stat("/dir/missing"); return err },
+			wantKeys: []string{"dir/file"},
+			wantCode: codeNoSuchFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, mock := newTestClient(t)
+			mock.Put("test", "dir/file", []byte("content"))
+
+			err := tt.cmd(client)
+			if got := errorCode(err); got != tt.wantCode {
+				t.Fatalf("error = %v, want code %v", err, tt.wantCode)
+			}
+
+			assertKeys(t, mock, tt.wantKeys...)
+		})
+	}
+}
+
+type fxCode int
+
+const (
+	codeOK fxCode = iota
+	codeNoSuchFile
+	codeFailure
+	codeOther
+)
+
+// errorCode classifies the errors returned by the sftp client,
+// which turns SSH_FX_NO_SUCH_FILE into fs.ErrNotExist.
+func errorCode(err error) fxCode {
+	var statusErr *sftp.StatusError
+
+	switch {
+	case err == nil:
+		return codeOK
+	case errors.Is(err, fs.ErrNotExist):
+		return codeNoSuchFile
+	case errors.As(err, &statusErr) && statusErr.FxCode() == sftp.ErrSSHFxFailure:
+		return codeFailure
+	}
+
+	return codeOther
+}
@@ -0,0 +1,39 @@
+package s3fs
+
+import (
+	"io/fs"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxKeyLength is the maximum length in bytes of a S3 key.
+const maxKeyLength = 1024
+
+// WithSanitizeKeys validates the keys written, including the prefix,
+// returning ErrInvalidKey for keys that are not valid UTF-8, hold control characters
+// (such as a newline or NUL) or are longer than 1024 bytes.
+func WithSanitizeKeys() Option {
+	return func(f *Fs) {
+		f.sanitizeKeys = true
+	}
+}
+
+// checkKey validates the key of the named file before writing it, when WithSanitizeKeys is set.
+func (f *Fs) checkKey(op, name string, elem ...string) error {
+	if !f.sanitizeKeys {
+		return nil
+	}
+
+	key := f.withPrefix(append([]string{name}, elem...)...)
+
+	valid := len(key) <= maxKeyLength && utf8.ValidString(key)
+	for _, r := range key {
+		valid = valid && !unicode.IsControl(r)
+	}
+
+	if !valid {
+		return &fs.PathError{Op: op, Path: name, Err: ErrInvalidKey}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			file: "dir/relatório ü.txt",
+		},
+		{
+			name:    "newline",
+			file:    "report\n.txt",
+			wantErr: true,
+		},
+		{
+			name:    "nul",
+			file:    "report\x00.txt",
+			wantErr: true,
+		},
+		{
+			name:    "invalid utf-8",
+			file:    "report\xff.txt",
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			file:    strings.Repeat("a", 1018),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t, WithPrefix("prefix"), WithSanitizeKeys())
+
+			f, err := fsys.Create(tt.file)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidKey) || !errors.Is(err, fs.ErrInvalid) {
+					t.Errorf("Create() error = %v, want %v", err, ErrInvalidKey)
+				}
+
+				if _, err := fsys.CreateDir(tt.file); !errors.Is(err, ErrInvalidKey) {
+					t.Errorf("CreateDir() error = %v, want %v", err, ErrInvalidKey)
+				}
+
+				if err := fsys.MkdirAll(context.Background(), tt.file); !errors.Is(err, ErrInvalidKey) {
+					t.Errorf("MkdirAll() error = %v, want %v", err, ErrInvalidKey)
+				}
+
+				if keys := client.Keys("test"); len(keys) != 0 {
+					t.Errorf("keys = %q, want none", keys)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSanitizeKeysDisabled(t *testing.T) {
+	fsys, client := newTestFs(t)
+
+	f, err := fsys.Create("report\n.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := client.Object("test", "report\n.txt"); !found {
+		t.Error("object not found")
+	}
+}
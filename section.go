@@ -0,0 +1,147 @@
+package s3fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// SectionReader reads a section of an object, see Fs.Section.
+type SectionReader struct {
+	*io.SectionReader
+	r *sectionReaderAt
+}
+
+// Close releases the pending request, if any.
+func (s *SectionReader) Close() error {
+	s.r.close()
+	return nil
+}
+
+// Section returns a reader of the length bytes of the named file starting at offset,
+// ending with io.EOF at the boundary.
+// The bytes are streamed by a GetObject bounded to the section, so the rest of the object is not downloaded;
+// reads out of sequence start a new request. The caller must close the reader.
+func (f *Fs) Section(ctx context.Context, name string, offset, length int64) (*SectionReader, error) {
+	info, err := f.StatWithContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "section", Path: name, Err: ErrIsDirectory}
+	}
+
+	if offset < 0 || length < 0 || offset > info.Size() {
+		return nil, &fs.PathError{Op: "section", Path: name, Err: fs.ErrInvalid}
+	}
+
+	length = min(length, info.Size()-offset)
+
+	r := &sectionReaderAt{ctx: ctx, fs: f, name: name, end: offset + length}
+
+	return &SectionReader{SectionReader: io.NewSectionReader(r, offset, length), r: r}, nil
+}
+
+// sectionReaderAt reads the object up to end from a ranged GetObject,
+// restarted when a read is not at the current position.
+type sectionReaderAt struct {
+	ctx    context.Context
+	fs     *Fs
+	body   io.ReadCloser
+	cancel context.CancelFunc
+	name   string
+	pos    int64
+	end    int64
+	mu     sync.Mutex
+}
+
+func (r *sectionReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if off >= r.end {
+		return 0, io.EOF
+	}
+
+	if r.body == nil || off != r.pos {
+		if err := r.open(off); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := io.ReadFull(r.body, p[:min(int64(len(p)), r.end-off)])
+	r.pos += int64(n)
+	r.fs.addBytes(BytesRead, n)
+	if waitErr := r.fs.waitRate(r.ctx, n); waitErr != nil {
+		r.release()
+		return n, waitErr
+	}
+
+	if err != nil {
+		r.release()
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return n, err
+	}
+
+	if r.pos == r.end {
+		r.release()
+		if n < len(p) {
+			return n, io.EOF
+		}
+	}
+
+	return n, nil
+}
+
+// open starts the GetObject of the bytes from off to the end of the section.
+func (r *sectionReaderAt) open(off int64) error {
+	r.release()
+
+	// as the downloads of File, the stream is not bound to the timeout
+	ctx, cancel := context.WithCancel(r.ctx)
+
+	res, err := r.fs.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.fs.bucket),
+		Key:    aws.String(r.fs.withPrefix(r.name)),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", off, r.end-1)),
+	})
+	if err != nil {
+		cancel()
+		return mapError(err)
+	}
+
+	r.body = res.Body
+	r.cancel = cancel
+	r.pos = off
+
+	return nil
+}
+
+// release closes the current request, if any.
+func (r *sectionReaderAt) release() {
+	if r.body != nil {
+		_ = r.body.Close()
+		r.body = nil
+	}
+
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+// close releases the request of a closed section.
+func (r *sectionReaderAt) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.release()
+}
@@ -0,0 +1,68 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestSection(t *testing.T) {
+	fsys, client := newTestFs(t)
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4<<20/16)
+	client.Put("test", "file", data)
+
+	client.Reset()
+
+	offset, length := int64(1<<20), int64(4<<10)
+
+	section, err := fsys.Section(context.Background(), "file", offset, length)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer section.Close()
+
+	got, err := io.ReadAll(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, data[offset:offset+length]) {
+		t.Errorf("Section() read %d bytes, want data[%d:%d]", len(got), offset, offset+length)
+	}
+
+	// a single GetObject of the section, nothing else is downloaded
+	var ranges []string
+	for _, call := range client.Calls() {
+		if in, ok := call.Input.(*s3.GetObjectInput); ok {
+			ranges = append(ranges, aws.ToString(in.Range))
+		}
+	}
+
+	if len(ranges) != 1 || ranges[0] != "bytes=1048576-1052671" {
+		t.Errorf("GetObject ranges = %v, want [bytes=1048576-1052671]", ranges)
+	}
+}
+
+func TestSectionInvalid(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("content"))
+	client.Put("test", "dir/file", nil)
+
+	if _, err := fsys.Section(context.Background(), "missing", 0, 1); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Section(missing) error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if _, err := fsys.Section(context.Background(), "dir", 0, 1); !errors.Is(err, ErrIsDirectory) {
+		t.Errorf("Section(dir) error = %v, want %v", err, ErrIsDirectory)
+	}
+
+	if _, err := fsys.Section(context.Background(), "file", 8, 1); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Section(file, 8) error = %v, want %v", err, fs.ErrInvalid)
+	}
+}
@@ -0,0 +1,125 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// StatStrategy is the S3 API used by Stat to find a file.
+type StatStrategy int
+
+const (
+	// ListBased finds files and directories with a single ListObjectsV2 call.
+	ListBased StatStrategy = iota
+	// HeadBased finds files with HeadObject,
+	// falling back to ListObjectsV2 for directories.
+	HeadBased
+	// AttributesBased finds files with GetObjectAttributes,
+	// falling back to ListObjectsV2 for directories.
+	AttributesBased
+)
+
+// WithStatStrategy sets the S3 API used by Stat, ListBased by default.
+// With HeadBased and AttributesBased, a file takes precedence over a directory of the same name.
+func WithStatStrategy(s StatStrategy) Option {
+	return func(f *Fs) {
+		f.statStrategy = s
+	}
+}
+
+// objectInfo returns the size and modification time of the object with the key.
+type objectInfo func(ctx context.Context, key string) (size int64, modTime time.Time, err error)
+
+// objectStat finds the named file with getInfo,
+// or the named directory by listing its prefix.
+func (f *Fs) objectStat(ctx context.Context, name string, getInfo objectInfo) (FileInfo, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	size, modTime, err := getInfo(ctx, f.withPrefix(name))
+	if err == nil {
+		return regularFileInfo(f.cleanPath(name), size, modTime), nil
+	}
+
+	if !isNotFound(err) {
+		return FileInfo{}, mapError(err)
+	}
+
+	res, err := f.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucket),
+		Prefix:  aws.String(f.withPrefix(name) + pathSeparator),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return FileInfo{}, mapError(err)
+	}
+
+	if len(res.Contents) > 0 {
+		return directoryFileInfo(f.cleanPath(name), f.now()), nil
+	}
+
+	return FileInfo{}, fs.ErrNotExist
+}
+
+func (f *Fs) headObject(ctx context.Context, key string) (int64, time.Time, error) {
+	res, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return getOrElse(res.ContentLength, zeroInt64), getOrElse(res.LastModified, f.now), nil
+}
+
+func (f *Fs) objectAttributes(ctx context.Context, key string) (int64, time.Time, error) {
+	res, err := f.client.GetObjectAttributes(ctx, &s3.GetObjectAttributesInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+		ObjectAttributes: []types.ObjectAttributes{
+			types.ObjectAttributesObjectSize,
+			types.ObjectAttributesEtag,
+			types.ObjectAttributesChecksum,
+		},
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return getOrElse(res.ObjectSize, zeroInt64), getOrElse(res.LastModified, f.now), nil
+}
+
+// Exists reports whether the named file or directory exists.
+func (f *Fs) Exists(ctx context.Context, name string) (bool, error) {
+	if _, err := f.StatWithContext(ctx, name); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// IsDir reports whether the named file exists and is a directory.
+func (f *Fs) IsDir(ctx context.Context, name string) (bool, error) {
+	info, err := f.StatWithContext(ctx, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return info.IsDir(), nil
+}
@@ -0,0 +1,113 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func TestStatStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       string
+		strategy StatStrategy
+	}{
+		{
+			name:     "list",
+			strategy: ListBased,
+			op:       "ListObjectsV2",
+		},
+		{
+			name:     "head",
+			strategy: HeadBased,
+			op:       "HeadObject",
+		},
+		{
+			name:     "attributes",
+			strategy: AttributesBased,
+			op:       "GetObjectAttributes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, client := newTestFs(t, WithPrefix("prefix"), WithStatStrategy(tt.strategy))
+			client.Put("test", "prefix/dir/file", []byte("content"))
+
+			info, err := fsys.Stat("dir/file")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.IsDir() || info.Size() != 7 || info.Name() != "dir/file" {
+				t.Errorf("Stat() = %s IsDir=%v Size=%d, want dir/file IsDir=false Size=7", info.Name(), info.IsDir(), info.Size())
+			}
+
+			calls := client.Calls()
+			if len(calls) != 1 || calls[0].Op != tt.op {
+				t.Errorf("calls = %v, want a single %s", calls, tt.op)
+			}
+
+			info, err = fsys.Stat("dir")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !info.IsDir() {
+				t.Errorf("Stat(dir) IsDir = false, want true")
+			}
+
+			if _, err := fsys.Stat("missing"); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Stat(missing) error = %v, want %v", err, fs.ErrNotExist)
+			}
+		})
+	}
+}
+
+func TestExistsIsDir(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "file", []byte("content"))
+	client.Put("test", "dir/file", []byte("content"))
+
+	tests := []struct {
+		name       string
+		wantExists bool
+		wantIsDir  bool
+	}{
+		{name: "file", wantExists: true},
+		{name: "dir", wantExists: true, wantIsDir: true},
+		{name: ".", wantExists: true, wantIsDir: true},
+		{name: "missing"},
+		{name: "dir/missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, err := fsys.Exists(context.Background(), tt.name)
+			if err != nil || exists != tt.wantExists {
+				t.Errorf("Exists() = (%v, %v), want (%v, nil)", exists, err, tt.wantExists)
+			}
+
+			isDir, err := fsys.IsDir(context.Background(), tt.name)
+			if err != nil || isDir != tt.wantIsDir {
+				t.Errorf("IsDir() = (%v, %v), want (%v, nil)", isDir, err, tt.wantIsDir)
+			}
+		})
+	}
+}
+
+func TestExistsError(t *testing.T) {
+	client := s3fstest.New()
+	fsys := New(client, "missing-bucket")
+
+	if _, err := fsys.Exists(context.Background(), "file"); !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("Exists() error = %v, want %v", err, ErrBucketNotFound)
+	}
+
+	if _, err := fsys.IsDir(context.Background(), "file"); !errors.Is(err, ErrBucketNotFound) {
+		t.Errorf("IsDir() error = %v, want %v", err, ErrBucketNotFound)
+	}
+}
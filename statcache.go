@@ -0,0 +1,110 @@
+package s3fs
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// statCacheSize is the maximum number of entries kept by the stat cache.
+const statCacheSize = 1024
+
+// statCache is a concurrency safe LRU of FileInfo keyed by cleaned name.
+// A nil *statCache is a disabled cache.
+type statCache struct {
+	now func() time.Time
+	// dir returns the parent directory of a name.
+	dir   func(string) string
+	ll    *list.List
+	items map[string]*list.Element
+	ttl   time.Duration
+	size  int
+	mu    sync.Mutex
+}
+
+type statCacheEntry struct {
+	expires time.Time
+	key     string
+	info    FileInfo
+}
+
+func newStatCache(ttl time.Duration, size int) *statCache {
+	return &statCache{
+		now:   time.Now,
+		dir:   parentDir,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		ttl:   ttl,
+		size:  size,
+	}
+}
+
+func (c *statCache) get(key string) (FileInfo, bool) {
+	if c == nil {
+		return FileInfo{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key]
+	if !found {
+		return FileInfo{}, false
+	}
+
+	entry := el.Value.(*statCacheEntry)
+	if c.now().After(entry.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return FileInfo{}, false
+	}
+
+	c.ll.MoveToFront(el)
+
+	return entry.info, true
+}
+
+func (c *statCache) set(key string, info FileInfo) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+
+	if el, found := c.items[key]; found {
+		el.Value = &statCacheEntry{key: key, info: info, expires: expires}
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&statCacheEntry{key: key, info: info, expires: expires})
+
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*statCacheEntry).key)
+	}
+}
+
+// invalidate removes the cleaned names from the cache, as well as their parent directories,
+// which may appear or disappear along with their contents.
+func (c *statCache) invalidate(names ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		for key := name; key != "" && key != currentDirName; key = c.dir(key) {
+			if el, found := c.items[key]; found {
+				c.ll.Remove(el)
+				delete(c.items, key)
+			}
+		}
+	}
+}
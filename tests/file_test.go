@@ -1,9 +1,12 @@
 package tests
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
@@ -11,7 +14,10 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -271,3 +277,197 @@ func TestFileStatHighNumberInNestedDirectory(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, path.Base(files[len(files)-1]), info.Name())
 }
+
+func TestFileTruncate(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "file.txt", strings.NewReader("hello world"))
+	fsClient := s3fs.New(client, "test")
+
+	f, err := fsClient.Create("file.txt")
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(5))
+	require.NoError(t, f.Close())
+
+	info, err := fsClient.Stat("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size())
+	assert.Equal(t, sha256sum(t, strings.NewReader("hello")), objectChecksum(t, "test", "file.txt"))
+}
+
+func TestFileTruncateAndWrite(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "file.txt", strings.NewReader("hello world"))
+	fsClient := s3fs.New(client, "test")
+
+	f, err := fsClient.Create("file.txt")
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(6))
+	_, err = f.Write([]byte("gopher"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, sha256sum(t, strings.NewReader("hello gopher")), objectChecksum(t, "test", "file.txt"))
+}
+
+func TestFileTruncateZero(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "file.txt", strings.NewReader("hello world"))
+	fsClient := s3fs.New(client, "test")
+
+	f, err := fsClient.Create("file.txt")
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(0))
+	require.NoError(t, f.Close())
+
+	info, err := fsClient.Stat("file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+}
+
+func TestFileTruncateInvalid(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "file.txt", strings.NewReader("hello world"))
+	fsClient := s3fs.New(client, "test")
+
+	f, err := fsClient.Create("file.txt")
+	require.NoError(t, err)
+	require.ErrorIs(t, f.Truncate(-1), fs.ErrInvalid)
+	require.ErrorIs(t, f.Truncate(100), fs.ErrInvalid)
+	require.NoError(t, f.Close())
+
+	r, err := fsClient.Open("file.txt")
+	require.NoError(t, err)
+	require.ErrorIs(t, r.(*s3fs.File).Truncate(5), fs.ErrInvalid)
+	require.NoError(t, r.Close())
+}
+
+func TestFileStatNameIsPrefixOfAnotherObject(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "foo", strings.NewReader("data"))
+	createObject(t, "test", "foo-extra.txt", strings.NewReader(""))
+	fsClient := s3fs.New(client, "test")
+
+	info, err := fsClient.Stat("foo")
+	require.NoError(t, err)
+	assert.False(t, info.IsDir())
+	assert.Equal(t, int64(4), info.Size())
+}
+
+func TestFileStatDirectoryIsPrefixOfAnotherObject(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "foo-extra.txt", strings.NewReader(""))
+	createObject(t, "test", "foo.txt", strings.NewReader(""))
+	createObject(t, "test", "foo/test.txt", strings.NewReader(""))
+	fsClient := s3fs.New(client, "test")
+
+	info, err := fsClient.Stat("foo")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestFileCreateWithACL(t *testing.T) {
+	createBucket(t, "test")
+	fsClient := s3fs.New(client, "test", s3fs.WithACL(types.ObjectCannedACLPublicRead))
+
+	f, err := fsClient.Create("file.txt")
+	require.NoError(t, err)
+	_, err = f.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assertPublicRead(t, "test", "file.txt")
+}
+
+func TestDirectoryCreateWithACL(t *testing.T) {
+	createBucket(t, "test")
+	fsClient := s3fs.New(client, "test", s3fs.WithACL(types.ObjectCannedACLPublicRead))
+
+	_, err := fsClient.CreateDir("some-directory")
+	require.NoError(t, err)
+
+	assertPublicRead(t, "test", "some-directory/.keep")
+}
+
+func TestFilePresignGetURL(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "some-directory/a/test.txt", strings.NewReader("content"))
+	fsClient := s3fs.New(client, "test", s3fs.WithPrefix("some-directory/a"))
+
+	signed, err := fsClient.PresignGetURL(context.Background(), "test.txt", 10*time.Minute)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:4566", u.Host)
+	assert.Equal(t, "/test/some-directory/a/test.txt", u.Path)
+	assert.Equal(t, "600", u.Query().Get("X-Amz-Expires"))
+
+	resp, err := http.Get(signed)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestFilePresignPutURL(t *testing.T) {
+	createBucket(t, "test")
+	fsClient := s3fs.New(client, "test", s3fs.WithPrefix("some-directory/a"))
+
+	signed, err := fsClient.PresignPutURL(context.Background(), "test.txt", time.Hour)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:4566", u.Host)
+	assert.Equal(t, "/test/some-directory/a/test.txt", u.Path)
+	assert.Equal(t, "3600", u.Query().Get("X-Amz-Expires"))
+
+	req, err := http.NewRequest(http.MethodPut, signed, strings.NewReader("content"))
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	data, err := fs.ReadFile(fsClient, "test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestFilePresignS3Options(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "test.txt", strings.NewReader("content"))
+	fsClient := s3fs.New(client, "test", s3fs.WithS3Options(func(opt *s3.Options) {
+		opt.EndpointResolverV2 = &staticResolverS3{endpoint: "http://127.0.0.1:4566"}
+	}))
+
+	signed, err := fsClient.PresignGetURL(context.Background(), "test.txt", time.Minute)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:4566", u.Host)
+
+	signed, err = fsClient.PresignPutURL(context.Background(), "new.txt", time.Minute)
+	require.NoError(t, err)
+
+	u, err = url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:4566", u.Host)
+}
+
+func TestFilePresignDirectory(t *testing.T) {
+	createBucket(t, "test")
+	createObject(t, "test", "some-directory/a/test.txt", strings.NewReader("content"))
+	fsClient := s3fs.New(client, "test")
+
+	_, err := fsClient.PresignGetURL(context.Background(), "some-directory", time.Minute)
+	require.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = fsClient.PresignPutURL(context.Background(), "some-directory", time.Minute)
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
@@ -271,3 +271,23 @@ func isErrNotFound(err error) bool {
 
 	return false
 }
+
+func assertPublicRead(t *testing.T, bucket, path string) {
+	t.Helper()
+
+	res, err := client.GetObjectAcl(context.Background(), &s3.GetObjectAclInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(path),
+	})
+	require.NoError(t, err)
+
+	for _, grant := range res.Grants {
+		if grant.Grantee != nil &&
+			aws.ToString(grant.Grantee.URI) == "http://acs.amazonaws.com/groups/global/AllUsers" &&
+			grant.Permission == types.PermissionRead {
+			return
+		}
+	}
+
+	t.Fatalf("object %s is not public-read: %+v", path, res.Grants)
+}
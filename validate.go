@@ -0,0 +1,79 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// NewValidated creates a new Fs like New,
+// returning the Validate error when the options are inconsistent,
+// the CheckRegion one when WithRegionCheck is set,
+// or ErrInvalidPrefix when the prefix set by WithPrefix is the key of an object.
+func NewValidated(client s3ApiClient, bucket string, opts ...Option) (*Fs, error) {
+	f := New(client, bucket, opts...)
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	if f.prefix != "" {
+		if _, err := f.rootStat(context.Background()); errors.Is(err, ErrInvalidPrefix) {
+			return nil, err
+		}
+	}
+
+	if f.regionCheck != nil {
+		if err := f.regionCheck.check(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Validate reports the options that would make the Fs fail at call time,
+// each one as an error matching ErrInvalidConfig.
+func (f *Fs) Validate() error {
+	var errs []error
+
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, args...)))
+	}
+
+	if baseClient(f.client) == nil {
+		invalid("client is required")
+	}
+
+	if f.bucket == "" {
+		invalid("bucket is required")
+	}
+
+	if strings.Contains(f.directoryFile, pathSeparator) {
+		invalid("directory file %q must not contain %q", f.directoryFile, pathSeparator)
+	}
+
+	if f.tempDir != "" {
+		if info, err := os.Stat(f.tempDir); err != nil || !info.IsDir() {
+			invalid("temporary directory %q is not a directory", f.tempDir)
+		}
+	}
+
+	if f.uploadPartSize > MaxPartSize {
+		invalid("upload part size %d is larger than %d", f.uploadPartSize, MaxPartSize)
+	}
+
+	if f.statStrategy < ListBased || f.statStrategy > AttributesBased {
+		invalid("unknown stat strategy %d", f.statStrategy)
+	}
+
+	if f.acl != "" && !slices.Contains(f.acl.Values(), f.acl) {
+		invalid("unknown ACL %q, expected one of %v", f.acl, types.ObjectCannedACL("").Values())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,122 @@
+package s3fs
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func TestNewValidated(t *testing.T) {
+	missingDir := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		client  s3ApiClient
+		name    string
+		bucket  string
+		wantErr string
+		opts    []Option
+	}{
+		{
+			name:   "valid",
+			client: s3fstest.New(),
+			bucket: "test",
+			opts:   []Option{WithTemporaryDirectory(t.TempDir()), WithACL(types.ObjectCannedACLPrivate), WithStatStrategy(HeadBased)},
+		},
+		{
+			name:    "no client",
+			bucket:  "test",
+			wantErr: "client is required",
+		},
+		{
+			name:    "no bucket",
+			client:  s3fstest.New(),
+			wantErr: "bucket is required",
+		},
+		{
+			name:    "nested directory file",
+			client:  s3fstest.New(),
+			bucket:  "test",
+			opts:    []Option{WithDirectoryFile("dir/.keep")},
+			wantErr: `directory file "dir/.keep" must not contain "/"`,
+		},
+		{
+			name:    "missing temporary directory",
+			client:  s3fstest.New(),
+			bucket:  "test",
+			opts:    []Option{WithTemporaryDirectory(missingDir)},
+			wantErr: "is not a directory",
+		},
+		{
+			name:    "upload part size too large",
+			client:  s3fstest.New(),
+			bucket:  "test",
+			opts:    []Option{WithUploadPartSize(6 * 1024 * 1024 * 1024)},
+			wantErr: "upload part size 6442450944 is larger than 5368709120",
+		},
+		{
+			name:    "unknown stat strategy",
+			client:  s3fstest.New(),
+			bucket:  "test",
+			opts:    []Option{WithStatStrategy(StatStrategy(42))},
+			wantErr: "unknown stat strategy 42",
+		},
+		{
+			name:    "unknown acl",
+			client:  s3fstest.New(),
+			bucket:  "test",
+			opts:    []Option{WithACL("everyone")},
+			wantErr: `unknown ACL "everyone"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys, err := NewValidated(tt.client, tt.bucket, tt.opts...)
+
+			if tt.wantErr == "" {
+				if err != nil || fsys == nil {
+					t.Fatalf("NewValidated() = (%v, %v), want a Fs", fsys, err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewValidated() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if fsys != nil {
+				t.Errorf("NewValidated() = %v, want nil", fsys)
+			}
+		})
+	}
+}
+
+func TestInvalidPrefix(t *testing.T) {
+	client := s3fstest.New()
+	client.Put("test", "data/file.txt", []byte("content"))
+	client.Put("test", "data/file.txt.bak", []byte("content"))
+
+	if _, err := NewValidated(client, "test", WithPrefix("data/file.txt")); !errors.Is(err, ErrInvalidPrefix) || !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("NewValidated() error = %v, want %v", err, ErrInvalidPrefix)
+	}
+
+	if _, err := NewValidated(client, "test", WithPrefix("data")); err != nil {
+		t.Errorf("NewValidated() error = %v, want nil", err)
+	}
+
+	fsys := New(client, "test", WithPrefix("data/file.txt"))
+
+	if _, err := fsys.Stat("."); !errors.Is(err, ErrInvalidPrefix) {
+		t.Errorf("Stat() error = %v, want %v", err, ErrInvalidPrefix)
+	}
+
+	if _, err := fsys.ReadDir("."); !errors.Is(err, ErrInvalidPrefix) {
+		t.Errorf("ReadDir() error = %v, want %v", err, ErrInvalidPrefix)
+	}
+}
@@ -0,0 +1,233 @@
+package s3fs
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectVersion is a version of an object of a versioned bucket.
+type ObjectVersion struct {
+	LastModified time.Time
+	VersionID    string
+	ETag         string
+	Size         int64
+	// IsLatest is set on the current version of the object.
+	IsLatest bool
+}
+
+// OpenVersion opens the given version of the named file for reading.
+func (f *Fs) OpenVersion(ctx context.Context, name, versionID string) (fs.File, error) {
+	headCtx := ctx
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		headCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(headCtx, &s3.HeadObjectInput{
+		Bucket:    aws.String(f.bucket),
+		Key:       aws.String(f.withPrefix(name)),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return nil, mapError(err)
+	}
+
+	file := &File{
+		ctx:       ctx,
+		fs:        f,
+		info:      regularFileInfo(f.cleanPath(name), aws.ToInt64(res.ContentLength), getOrElse(res.LastModified, f.now)),
+		versionID: aws.String(versionID),
+	}
+	return file, file.openReaderAt(ctx, 0)
+}
+
+// ListVersions returns the versions of the named file, from the newest to the oldest.
+// Delete markers are not listed, fs.ErrNotExist is returned when the file has no versions.
+func (f *Fs) ListVersions(ctx context.Context, name string) ([]ObjectVersion, error) {
+	key := f.withPrefix(name)
+
+	paginator := s3.NewListObjectVersionsPaginator(f.client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(f.bucket),
+		Prefix: aws.String(key),
+	})
+
+	var versions []ObjectVersion
+
+	for paginator.HasMorePages() {
+		pageCtx, cancelFn := ctx, context.CancelFunc(func() {})
+		if f.timeout > 0 {
+			pageCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		}
+
+		page, err := paginator.NextPage(pageCtx)
+		cancelFn()
+		if err != nil {
+			return nil, mapError(err)
+		}
+
+		for _, v := range page.Versions {
+			// the prefix also matches the keys starting with the name
+			if aws.ToString(v.Key) != key {
+				continue
+			}
+
+			versions = append(versions, ObjectVersion{
+				VersionID:    aws.ToString(v.VersionId),
+				ETag:         aws.ToString(v.ETag),
+				Size:         aws.ToInt64(v.Size),
+				LastModified: getOrElse(v.LastModified, f.now),
+				IsLatest:     aws.ToBool(v.IsLatest),
+			})
+		}
+	}
+
+	if len(versions) == 0 {
+		return nil, &fs.PathError{Op: "versions", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return versions, nil
+}
+
+// versionsDir is the virtual directory listing the versions of the files of its parent,
+// see WithVersionBrowsing.
+const versionsDir = ".versions"
+
+// WithVersionBrowsing presents the versions of the files of each directory
+// in a virtual .versions directory: ReadDir of dir/.versions lists a directory per file,
+// ReadDir of dir/.versions/file lists its versions named by version ID,
+// and Open of dir/.versions/file/id reads that version.
+// The virtual directories are listed with ReadDir and Stat, they can not be opened nor walked.
+func WithVersionBrowsing() Option {
+	return func(f *Fs) {
+		f.versionBrowsing = true
+	}
+}
+
+// versionPath is a path under a virtual .versions directory.
+type versionPath struct {
+	// dir is the directory holding the .versions directory
+	dir     string
+	file    string
+	version string
+	// depth is the number of elements after .versions
+	depth int
+}
+
+// parseVersionPath reports whether name is under a virtual .versions directory.
+func (f *Fs) parseVersionPath(name string) (versionPath, bool) {
+	if !f.versionBrowsing {
+		return versionPath{}, false
+	}
+
+	parts := strings.Split(f.cleanPath(name), pathSeparator)
+
+	i := slices.Index(parts, versionsDir)
+	if i < 0 || len(parts)-i > 3 {
+		return versionPath{}, false
+	}
+
+	vp := versionPath{dir: strings.Join(parts[:i], pathSeparator), depth: len(parts) - i - 1}
+	if vp.depth > 0 {
+		vp.file = parts[i+1]
+	}
+	if vp.depth > 1 {
+		vp.version = parts[i+2]
+	}
+
+	return vp, true
+}
+
+func (vp versionPath) fileName() string {
+	return path.Join(vp.dir, vp.file)
+}
+
+// versionStat returns the FileInfo of a virtual path.
+func (f *Fs) versionStat(ctx context.Context, name string, vp versionPath) (FileInfo, error) {
+	switch vp.depth {
+	case 0:
+		return directoryFileInfo(versionsDir, f.now()), nil
+
+	case 1:
+		if _, err := f.ListVersions(ctx, vp.fileName()); err != nil {
+			return FileInfo{}, err
+		}
+		return directoryFileInfo(vp.file, f.now()), nil
+	}
+
+	versions, err := f.ListVersions(ctx, vp.fileName())
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	for _, v := range versions {
+		if v.VersionID == vp.version {
+			return regularFileInfo(v.VersionID, v.Size, v.LastModified), nil
+		}
+	}
+
+	return FileInfo{}, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// versionReadDir lists a virtual directory.
+func (f *Fs) versionReadDir(ctx context.Context, name string, vp versionPath) ([]fs.DirEntry, error) {
+	switch vp.depth {
+	case 0:
+		result := []fs.DirEntry{}
+		err := f.rangeDir(ctx, vp.dir, func(entry fs.DirEntry) error {
+			if !entry.IsDir() {
+				result = append(result, &Directory{
+					fs:       f,
+					name:     f.child(f.child(vp.dir, versionsDir), entry.Name()),
+					fileInfo: directoryFileInfo(entry.Name(), f.now()),
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+
+		return result, nil
+
+	case 1:
+		versions, err := f.ListVersions(ctx, vp.fileName())
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]fs.DirEntry, 0, len(versions))
+		for _, v := range versions {
+			result = append(result, &File{
+				fs:   f,
+				info: regularFileInfo(v.VersionID, v.Size, v.LastModified),
+			})
+		}
+
+		return result, nil
+	}
+
+	return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotDirectory}
+}
+
+// versionOpen opens a version through its virtual path.
+func (f *Fs) versionOpen(ctx context.Context, name string, vp versionPath) (fs.File, error) {
+	if vp.depth < 2 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
+	}
+
+	return f.OpenVersion(ctx, vp.fileName(), vp.version)
+}
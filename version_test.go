@@ -0,0 +1,162 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"testing"
+
+	"github.com/jacoelho/s3fs/s3fstest"
+)
+
+func TestVersions(t *testing.T) {
+	client := s3fstest.New()
+	client.EnableVersioning("test")
+	fsys := New(client, "test", WithPrefix("prefix"))
+	ctx := context.Background()
+
+	for _, content := range []string{"first", "second"} {
+		f, err := fsys.Create("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := io.WriteString(f, content); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Put("test", "prefix/file.bak", []byte("backup"))
+
+	versions, err := fsys.ListVersions(ctx, "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(versions) != 2 {
+		t.Fatalf("ListVersions() = %d versions, want 2", len(versions))
+	}
+
+	if !versions[0].IsLatest || versions[1].IsLatest {
+		t.Errorf("IsLatest = [%v %v], want [true false]", versions[0].IsLatest, versions[1].IsLatest)
+	}
+
+	if versions[1].Size != int64(len("first")) {
+		t.Errorf("Size = %d, want %d", versions[1].Size, len("first"))
+	}
+
+	f, err := fsys.OpenVersion(ctx, "file", versions[1].VersionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "first" {
+		t.Errorf("content = %q, want %q", got, "first")
+	}
+}
+
+func TestVersionsNotExist(t *testing.T) {
+	client := s3fstest.New()
+	client.EnableVersioning("test")
+	client.Put("test", "file", []byte("content"))
+	fsys := New(client, "test")
+	ctx := context.Background()
+
+	if _, err := fsys.ListVersions(ctx, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ListVersions() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if _, err := fsys.OpenVersion(ctx, "file", "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("OpenVersion() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestVersionBrowsing(t *testing.T) {
+	client := s3fstest.New()
+	client.EnableVersioning("test")
+	fsys := New(client, "test", WithVersionBrowsing())
+
+	for _, content := range []string{"first", "second"} {
+		client.Put("test", "dir/file", []byte(content))
+	}
+	client.Put("test", "dir/other", []byte("other"))
+	client.Put("test", "dir/sub/file", []byte("nested"))
+
+	names := func(entries []fs.DirEntry) []string {
+		var result []string
+		for _, entry := range entries {
+			result = append(result, entry.Name())
+		}
+		return result
+	}
+
+	entries, err := fsys.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(names(entries)), "[. .versions file other sub]"; got != want {
+		t.Errorf("ReadDir(dir) = %v, want %v", got, want)
+	}
+
+	entries, err = fsys.ReadDir("dir/.versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(names(entries)), "[file other]"; got != want {
+		t.Errorf("ReadDir(dir/.versions) = %v, want %v", got, want)
+	}
+
+	versions, err := fsys.ReadDir("dir/.versions/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(versions) != 2 {
+		t.Fatalf("ReadDir(dir/.versions/file) = %v, want 2 versions", names(versions))
+	}
+
+	// the versions are listed from the newest
+	oldest := path.Join("dir/.versions/file", versions[1].Name())
+
+	info, err := fsys.Stat(oldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() != int64(len("first")) {
+		t.Errorf("Stat(%s).Size() = %d, want %d", oldest, info.Size(), len("first"))
+	}
+
+	f, err := fsys.Open(oldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "first" {
+		t.Errorf("content = %q, want %q", got, "first")
+	}
+
+	if _, err := fsys.Stat("dir/.versions/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(dir/.versions/missing) error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -0,0 +1,232 @@
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// walkKey is a key listed under the walk root.
+type walkKey struct {
+	modTime time.Time
+	// name is the key relative to the root
+	name string
+	// order is name with the separator sorting before any other byte,
+	// so the keys follow the order of fs.WalkDir.
+	order string
+	etag  string
+	size  int64
+}
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or directory,
+// with the same semantics and order as fs.WalkDir.
+// Directories are synthesized from a single listing of every key under root,
+// instead of listing each directory, and the keys are held in memory.
+func (f *Fs) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return f.WalkDirWithContext(context.Background(), root, fn)
+}
+
+// WalkDirWithContext walks the file tree rooted at root, calling fn for each file or directory,
+// with the same semantics and order as fs.WalkDir.
+// Directories are synthesized from a single listing of every key under root,
+// instead of listing each directory, and the keys are held in memory.
+func (f *Fs) WalkDirWithContext(ctx context.Context, root string, fn fs.WalkDirFunc) error {
+	keys, err := f.listKeys(ctx, root)
+	if errors.Is(err, ErrListingTruncated) {
+		// walks the keys of the first pages, then reports the truncation
+		if walkErr := f.walkKeys(ctx, root, keys, fn); walkErr != nil {
+			return walkErr
+		}
+		return err
+	}
+	if err != nil {
+		return fn(root, nil, err)
+	}
+
+	return f.walkKeys(ctx, root, keys, fn)
+}
+
+// walkKeys calls fn for the root and the files and directories of keys, listed under root.
+func (f *Fs) walkKeys(ctx context.Context, root string, keys []walkKey, fn fs.WalkDirFunc) error {
+	if len(keys) == 0 && f.cleanPath(root) != "" {
+		// not a directory, root is either a file or does not exist
+		info, err := f.rootFileInfo(ctx, root)
+		if err != nil {
+			return fn(root, nil, err)
+		}
+
+		err = fn(root, &File{fs: f, info: info}, nil)
+		if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+			return nil
+		}
+		return err
+	}
+
+	rootDir := &Directory{fs: f, name: f.cleanPath(root), fileInfo: directoryFileInfo(path.Base(path.Clean(root)), f.now())}
+	if err := fn(root, rootDir, nil); err != nil {
+		if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+			return nil
+		}
+		return err
+	}
+
+	// openDirs are the directories emitted that may hold the following keys
+	var (
+		openDirs []string
+		skip     string
+	)
+
+	for i, key := range keys {
+		if skip != "" && strings.HasPrefix(key.name, skip) {
+			continue
+		}
+		skip = ""
+
+		for len(openDirs) > 0 && !strings.HasPrefix(key.name, openDirs[len(openDirs)-1]+pathSeparator) {
+			openDirs = openDirs[:len(openDirs)-1]
+		}
+
+		parent := ""
+		if len(openDirs) > 0 {
+			parent = openDirs[len(openDirs)-1] + pathSeparator
+		}
+
+		// directories not emitted yet
+		skipped := false
+		for rest := strings.TrimPrefix(key.name, parent); strings.Contains(rest, pathSeparator); {
+			i := strings.Index(rest, pathSeparator)
+			dir := parent + rest[:i]
+			rest = rest[i+1:]
+
+			err := fn(path.Join(root, dir), &Directory{fs: f, name: f.cleanPath(path.Join(root, dir)), fileInfo: directoryFileInfo(path.Base(dir), f.now())}, nil)
+			if errors.Is(err, fs.SkipDir) {
+				skip = dir + pathSeparator
+				skipped = true
+				break
+			}
+			if err != nil {
+				if errors.Is(err, fs.SkipAll) {
+					return nil
+				}
+				return err
+			}
+
+			openDirs = append(openDirs, dir)
+			parent = dir + pathSeparator
+		}
+
+		name := path.Base(key.name)
+		if skipped || strings.HasSuffix(key.name, pathSeparator) || name == f.directoryFile {
+			continue
+		}
+
+		// a file and a directory of the same name resolve to the directory,
+		// whose keys follow the one of the file in walk order
+		if i+1 < len(keys) && strings.HasPrefix(keys[i+1].name, key.name+pathSeparator) {
+			continue
+		}
+
+		err := fn(path.Join(root, key.name), &File{fs: f, info: regularFileInfo(name, key.size, key.modTime)}, nil)
+		if errors.Is(err, fs.SkipDir) {
+			// skips the remaining files of the directory
+			if parent == "" {
+				return nil
+			}
+			skip = parent
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, fs.SkipAll) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listKeys lists every key under the root directory, sorted in walk order.
+func (f *Fs) listKeys(ctx context.Context, root string) ([]walkKey, error) {
+	prefix := f.withPrefix(root) + pathSeparator
+	if prefix == pathSeparator {
+		prefix = ""
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(f.client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: f.pageSize(),
+	})
+
+	var (
+		keys      []walkKey
+		truncated error
+	)
+
+	for pages := 0; paginator.HasMorePages(); pages++ {
+		if f.pageLimitReached(pages) {
+			truncated = &fs.PathError{Op: "walk", Path: root, Err: ErrListingTruncated}
+			break
+		}
+
+		pageCtx, cancelFn := ctx, context.CancelFunc(func() {})
+		if f.timeout > 0 {
+			pageCtx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		}
+
+		page, err := paginator.NextPage(pageCtx)
+		cancelFn()
+		if err != nil {
+			return nil, mapError(err)
+		}
+
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if name == "" {
+				continue
+			}
+
+			keys = append(keys, walkKey{
+				name:    name,
+				order:   strings.ReplaceAll(name, pathSeparator, "\x00"),
+				etag:    aws.ToString(obj.ETag),
+				size:    getOrElse(obj.Size, zeroInt64),
+				modTime: getOrElse(obj.LastModified, f.now),
+			})
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].order < keys[j].order })
+
+	return keys, truncated
+}
+
+// rootFileInfo returns the FileInfo of a walk root which is not a directory.
+func (f *Fs) rootFileInfo(ctx context.Context, root string) (FileInfo, error) {
+	if f.timeout > 0 {
+		var cancelFn context.CancelFunc
+		ctx, cancelFn = context.WithTimeout(ctx, f.timeout)
+		defer cancelFn()
+	}
+
+	res, err := f.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(f.withPrefix(root)),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return FileInfo{}, &fs.PathError{Op: "walk", Path: root, Err: fs.ErrNotExist}
+		}
+		return FileInfo{}, mapError(err)
+	}
+
+	return regularFileInfo(path.Base(f.cleanPath(root)), aws.ToInt64(res.ContentLength), getOrElse(res.LastModified, f.now)), nil
+}
@@ -0,0 +1,204 @@
+package s3fs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var walkKeys = []string{
+	"a.txt",
+	"a/b/c.txt",
+	"a/b-c.txt",
+	"a/b/d/e.txt",
+	"a-b/f.txt",
+	"empty/.keep",
+	"g/h.txt",
+	"g/i/j.txt",
+	"g/i/k.txt",
+	"g/l.txt",
+	"z.txt",
+}
+
+// walkTrace records the calls of a WalkDirFunc, returning the result of skip for each path.
+func walkTrace(got *[]string, skip func(string) error) fs.WalkDirFunc {
+	return func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			*got = append(*got, fmt.Sprintf("%s error", p))
+			return err
+		}
+
+		kind := "file"
+		if d.IsDir() {
+			kind = "dir"
+		}
+		*got = append(*got, fmt.Sprintf("%s %s %s", p, kind, d.Name()))
+
+		return skip(p)
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	tests := []struct {
+		skip func(string) error
+		name string
+		root string
+	}{
+		{
+			name: "all",
+			root: ".",
+			skip: func(string) error { return nil },
+		},
+		{
+			name: "nested root",
+			root: "g",
+			skip: func(string) error { return nil },
+		},
+		{
+			name: "file root",
+			root: "g/h.txt",
+			skip: func(string) error { return nil },
+		},
+		{
+			name: "skip dir",
+			root: ".",
+			skip: func(p string) error {
+				if p == "a/b" || p == "g/i" {
+					return fs.SkipDir
+				}
+				return nil
+			},
+		},
+		{
+			name: "skip dir on file",
+			root: ".",
+			skip: func(p string) error {
+				if p == "g/h.txt" {
+					return fs.SkipDir
+				}
+				return nil
+			},
+		},
+		{
+			name: "skip all",
+			root: ".",
+			skip: func(p string) error {
+				if p == "a/b/d" {
+					return fs.SkipAll
+				}
+				return nil
+			},
+		},
+	}
+
+	mapFS := fstest.MapFS{}
+	fsys, client := newTestFs(t)
+
+	for _, key := range walkKeys {
+		client.Put("test", key, []byte(key))
+
+		if path.Base(key) == directoryFile {
+			mapFS[path.Dir(key)] = &fstest.MapFile{Mode: fs.ModeDir}
+			continue
+		}
+		mapFS[key] = &fstest.MapFile{Data: []byte(key)}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want, got []string
+
+			if err := fs.WalkDir(mapFS, tt.root, walkTrace(&want, tt.skip)); err != nil {
+				t.Fatal(err)
+			}
+
+			client.Reset()
+
+			if err := fsys.WalkDir(tt.root, walkTrace(&got, tt.skip)); err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("WalkDir() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+
+			if got := client.Count("ListObjectsV2"); got > 1 {
+				t.Errorf("ListObjectsV2 calls = %d, want at most 1", got)
+			}
+		})
+	}
+}
+
+func TestWalkDirFileAndDirectory(t *testing.T) {
+	fsys, client := newTestFs(t)
+	client.Put("test", "a", []byte("a"))
+	client.Put("test", "a/b.txt", []byte("b"))
+	client.Put("test", "c", []byte("c"))
+
+	var got []string
+
+	if err := fsys.WalkDir(".", walkTrace(&got, func(string) error { return nil })); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file a is shadowed by the directory a, as in ReadDir
+	want := []string{
+		". dir .",
+		"a dir a",
+		"a/b.txt file b.txt",
+		"c file c",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("WalkDir() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWalkDirNotExist(t *testing.T) {
+	fsys, _ := newTestFs(t)
+
+	var got []string
+
+	err := fsys.WalkDir("missing", walkTrace(&got, func(string) error { return nil }))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WalkDir() error = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	if len(got) != 1 || got[0] != "missing error" {
+		t.Errorf("WalkDir() calls = %v, want [missing error]", got)
+	}
+}
+
+func TestWalkDirPaginated(t *testing.T) {
+	fsys, client := newTestFs(t)
+	for i := 0; i < 2500; i++ {
+		client.Put("test", fmt.Sprintf("dir%d/sub/file%04d", i%3, i), nil)
+	}
+
+	files := 0
+	err := fsys.WalkDir(".", func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if files != 2500 {
+		t.Errorf("files = %d, want 2500", files)
+	}
+
+	// a single listing of 3 pages, regardless of the number of directories
+	if got := client.Count("ListObjectsV2"); got != 3 {
+		t.Errorf("ListObjectsV2 calls = %d, want 3", got)
+	}
+}